@@ -2,6 +2,7 @@ package main
 
 import (
 	"Blog/internal/auth"
+	"Blog/internal/blob"
 	"Blog/internal/env"
 	"Blog/internal/mailer"
 	ratelimiter "Blog/internal/rateLimiter"
@@ -12,6 +13,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -30,6 +32,11 @@ type application struct {
 	mailer      mailer.Client
 	auth        auth.JWTAuthenticator
 	rateLimiter ratelimiter.Limiter
+	// resetLimiter throttles password reset mails per email address
+	resetLimiter ratelimiter.Limiter
+	blobs        blob.BlobStore
+	// tasks tracks work started by background, waited on at shutdown
+	tasks sync.WaitGroup
 }
 
 type dbConfig struct {
@@ -44,9 +51,41 @@ type config struct {
 	db          dbConfig
 	env         string
 	frontendURL string
+	// apiURL is where clients reach this server, for links to its own routes
+	apiURL      string
 	mail        mailConfig
 	auth        authConfig
 	rateLimiter ratelimiter.Config
+	publisher   publisherConfig
+	comments    commentConfig
+	reactions   reactionConfig
+	ranking     store.RankingConfig
+	profile     profileConfig
+	blob        blobConfig
+	upload      uploadConfig
+}
+
+type blobConfig struct {
+	backend   string
+	localDir  string
+	publicURL string
+	s3        blob.S3Config
+}
+
+type uploadConfig struct {
+	maxAvatarSize int64
+	maxImageSize  int64
+	thumbnailSize int
+}
+
+type profileConfig struct {
+	usernameCooldown    time.Duration
+	usernameReservation time.Duration
+}
+
+type commentConfig struct {
+	maxDepth     int
+	previewCount int
 }
 
 type authConfig struct {
@@ -66,9 +105,12 @@ type basicConfig struct {
 }
 
 type mailConfig struct {
-	exp       time.Duration
-	fromEmail string
-	apiKey    string
+	exp         time.Duration
+	resetExp    time.Duration
+	resetLimit  int
+	resetWindow time.Duration
+	fromEmail   string
+	apiKey      string
 }
 
 func (app *application) mount() *chi.Mux {
@@ -81,9 +123,9 @@ func (app *application) mount() *chi.Mux {
 	r.Use(middleware.Timeout(60 * time.Second))
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{env.GetString("LOCAL_FRONTEND_URL", "http://localhost:3000")},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "If-Match"},
+		ExposedHeaders:   []string{"Link", "ETag"},
 		AllowCredentials: false,
 		MaxAge:           300,
 	}))
@@ -95,36 +137,129 @@ func (app *application) mount() *chi.Mux {
 		r.With(app.BasicAuthMiddleware())
 		docsURL := fmt.Sprintf("%s/swagger/doc.json", app.config.addr)
 		r.Get("/swagger/*", httpSwagger.Handler(httpSwagger.URL(docsURL)))
+		r.With(app.OptionalAuthenTokenMiddleware()).Get("/search", app.searchHandler)
+		// only avatars are public, post images go through the posts routes
+		if local, ok := app.blobs.(*blob.LocalStore); ok {
+			r.Handle("/media/avatars/*", local.Handler("/v1/media/"))
+		}
 		r.Route("/posts", func(r chi.Router) {
-			r.Use(app.AuthenTokenMiddleware())
-			r.Post("/", app.createPostHandler)
-			r.Route("/{postId}", func(r chi.Router) {
-				r.Use(app.postsContextMiddleware)
-				r.Get("/", app.getPostHanlder)
-				r.Delete("/", app.CheckPostOwnership("admin", app.deletePostHandler))
-				r.Patch("/", app.CheckPostOwnership("moderator", app.updatePostHandler))
-				r.Post("/comments", app.postCommentHandler)
+			r.With(app.OptionalAuthenTokenMiddleware()).Get("/", app.getExploreFeedHandler)
+			r.With(app.OptionalAuthenTokenMiddleware()).Get("/search", app.searchPostsHandler)
+			r.Group(func(r chi.Router) {
+				r.Use(app.AuthenTokenMiddleware())
+				r.Post("/", app.createPostHandler)
+				r.Get("/drafts", app.getUserDraftsHandler)
+				r.Route("/{postId}", func(r chi.Router) {
+					// CheckPostOwnership loads the post itself so moderators
+					// reach posts hidden from them
+					r.Delete("/", app.CheckPostOwnership("admin", app.deletePostHandler))
+					r.Patch("/", app.CheckPostOwnership("moderator", app.updatePostHandler))
+					r.Put("/publish", app.CheckPostOwnership("moderator", app.publishPostHandler))
+					r.Put("/unpublish", app.CheckPostOwnership("moderator", app.unpublishPostHandler))
+					r.Put("/archive", app.CheckPostOwnership("moderator", app.archivePostHandler))
+					r.Put("/schedule", app.CheckPostOwnership("moderator", app.schedulePostHandler))
+					// revisions keep content the author removed, so only those who
+					// may edit the post read them
+					r.Get("/revisions", app.CheckPostOwnership("moderator", app.getPostRevisionsHandler))
+					r.Get("/revisions/diff", app.CheckPostOwnership("moderator", app.diffPostRevisionsHandler))
+					r.Get("/revisions/{version}", app.CheckPostOwnership("moderator", app.getPostRevisionHandler))
+					r.Post("/revisions/{version}/restore", app.CheckPostOwnership("moderator", app.restorePostRevisionHandler))
+					r.Post("/images", app.CheckPostOwnership("moderator", app.uploadPostImageHandler))
+					r.Delete("/images/{imageId}", app.CheckPostOwnership("moderator", app.deletePostImageHandler))
+					r.Group(func(r chi.Router) {
+						r.Use(app.postsContextMiddleware)
+						r.Get("/", app.getPostHanlder)
+						r.Put("/like", app.likePostHandler)
+						r.Put("/unlike", app.unlikePostHandler)
+						r.Put("/react", app.reactPostHandler)
+						r.Put("/unreact", app.unreactPostHandler)
+						r.Get("/images", app.getPostImagesHandler)
+						r.Get("/images/{imageId}", app.getPostImageFileHandler)
+						r.Get("/images/{imageId}/thumbnail", app.getPostImageThumbnailHandler)
+						r.Route("/comments", func(r chi.Router) {
+							r.Post("/", app.postCommentHandler)
+							r.Get("/", app.getPostCommentsHandler)
+							r.Get("/tree", app.getCommentTreeHandler)
+							r.Route("/{commentId}", func(r chi.Router) {
+								r.Use(app.commentsContextMiddleware)
+								r.Get("/replies", app.getCommentRepliesHandler)
+								r.Put("/like", app.likeCommentHandler)
+								r.Put("/unlike", app.unlikeCommentHandler)
+								r.Patch("/", app.CheckCommentOwnership("", app.updateCommentHandler))
+								r.Delete("/", app.CheckCommentOwnership("moderator", app.deleteCommentHandler))
+							})
+						})
+					})
+				})
 			})
 		})
 		r.Route("/users", func(r chi.Router) {
 			r.Put("/activate/{token}", app.userActivationHandler)
 			r.Route("/{userId}", func(r chi.Router) {
-				r.Use(app.AuthenTokenMiddleware())
 				r.Use(app.usersContextMiddleware)
-				r.Get("/", app.getUserHandler)
-				r.Put("/follow", app.followUserHandler)
-				r.Put("/unfollow", app.unfollowUserHandler)
+				r.With(app.OptionalAuthenTokenMiddleware()).Get("/posts", app.getUserPostsHandler)
+				r.With(app.OptionalAuthenTokenMiddleware()).Get("/followers", app.getUserFollowersHandler)
+				r.With(app.OptionalAuthenTokenMiddleware()).Get("/following", app.getUserFollowingHandler)
+				r.Group(func(r chi.Router) {
+					r.Use(app.AuthenTokenMiddleware())
+					r.Get("/", app.getUserHandler)
+					r.Put("/follow", app.followUserHandler)
+					r.Put("/unfollow", app.unfollowUserHandler)
+					r.Put("/block", app.blockUserHandler)
+					r.Put("/unblock", app.unblockUserHandler)
+					r.Put("/mute", app.muteUserHandler)
+					r.Put("/unmute", app.unmuteUserHandler)
+				})
 			})
 			r.Group(func(r chi.Router) {
 				r.Use(app.AuthenTokenMiddleware())
 				r.Get("/feed", app.getUserFeedHandler)
 				r.Get("/friends", app.getUserSearchFriend)
+				r.Get("/blocked", app.getBlockedUsersHandler)
+				r.Get("/muted", app.getMutedUsersHandler)
+				r.Patch("/me", app.updateProfileHandler)
+				r.Put("/me/privacy", app.updatePrivacyHandler)
+				r.Put("/me/avatar", app.uploadAvatarHandler)
+				r.Delete("/me/avatar", app.deleteAvatarHandler)
+				r.Route("/requests", func(r chi.Router) {
+					r.Get("/incoming", app.getIncomingRequestsHandler)
+					r.Get("/outgoing", app.getOutgoingRequestsHandler)
+					r.Put("/{requesterId}/approve", app.approveRequestHandler)
+					r.Put("/{requesterId}/reject", app.rejectRequestHandler)
+				})
+			})
+		})
+		r.Route("/tags", func(r chi.Router) {
+			r.Get("/", app.listTagsHandler)
+			r.Get("/trending", app.trendingTagsHandler)
+			r.With(app.OptionalAuthenTokenMiddleware()).Get("/{tag}/posts", app.getTagPostsHandler)
+			r.Group(func(r chi.Router) {
+				r.Use(app.AuthenTokenMiddleware())
+				r.Get("/following", app.getFollowedTagsHandler)
+				r.Put("/{tag}/follow", app.followTagHandler)
+				r.Put("/{tag}/unfollow", app.unfollowTagHandler)
+				r.Post("/merge", app.CheckRole("admin", app.mergeTagsHandler))
+				r.Put("/{tag}", app.CheckRole("admin", app.renameTagHandler))
+			})
+		})
+		r.Route("/bookmarks", func(r chi.Router) {
+			r.Use(app.AuthenTokenMiddleware())
+			r.Get("/", app.getReadingListsHandler)
+			r.Post("/", app.createReadingListHandler)
+			r.Route("/{listId}", func(r chi.Router) {
+				r.Use(app.readingListContextMiddleware)
+				r.Get("/", app.getReadingListPostsHandler)
+				r.Delete("/", app.deleteReadingListHandler)
+				r.Put("/posts/{postId}", app.addBookmarkHandler)
+				r.Delete("/posts/{postId}", app.removeBookmarkHandler)
 			})
 		})
 		// Public routes
 		r.Route("/authentication", func(r chi.Router) {
 			r.Post("/user", app.userRegisterHandler)
 			r.Post("/token", app.createTokenHandler)
+			r.Post("/password/forgot", app.forgotPasswordHandler)
+			r.Put("/password/reset", app.resetPasswordHandler)
 		})
 	})
 	// posts
@@ -133,6 +268,21 @@ func (app *application) mount() *chi.Mux {
 	return r
 }
 
+// background runs fn outside the request, logging instead of crashing on
+// a panic. The server waits for it before shutting down.
+func (app *application) background(fn func()) {
+	app.tasks.Add(1)
+	go func() {
+		defer app.tasks.Done()
+		defer func() {
+			if err := recover(); err != nil {
+				app.logger.Errorw("background task panicked", "error", err)
+			}
+		}()
+		fn()
+	}()
+}
+
 func (app *application) run(mux *chi.Mux) error {
 
 	docs.SwaggerInfo.Version = version
@@ -144,6 +294,18 @@ func (app *application) run(mux *chi.Mux) error {
 		ReadTimeout:  time.Second * 10,
 		IdleTimeout:  time.Minute,
 	}
+	// background workers run until the server shuts down
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var workers sync.WaitGroup
+	if app.config.publisher.enabled {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			app.runScheduledPublisher(workerCtx)
+		}()
+	}
+
 	shutdown := make(chan error)
 	go func() {
 		quit := make(chan os.Signal, 1)
@@ -152,7 +314,11 @@ func (app *application) run(mux *chi.Mux) error {
 		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 		defer cancel()
 		app.logger.Infow("signal caught", "sginal", s.String())
-		shutdown <- srv.Shutdown(ctx)
+		err := srv.Shutdown(ctx)
+		stopWorkers()
+		workers.Wait()
+		app.tasks.Wait()
+		shutdown <- err
 	}()
 	app.logger.Infow("server has started", "addr", app.config.addr, "env", app.config.env)
 	err := srv.ListenAndServe()
@@ -51,3 +51,25 @@ func TestRateLimiterMiddleware(t *testing.T) {
 		}
 	}
 }
+
+func TestEtagMatches(t *testing.T) {
+	cases := []struct {
+		ifMatch string
+		etag    string
+		want    bool
+	}{
+		{`"3"`, `"3"`, true},
+		{`"2"`, `"3"`, false},
+		{`"1", "3"`, `"3"`, true},
+		{`*`, `"3"`, true},
+		{` * `, `"3"`, true},
+		{`W/"3"`, `"3"`, true},
+		{`W/"2", W/"3"`, `"3"`, true},
+		{`W/"2"`, `"3"`, false},
+	}
+	for _, c := range cases {
+		if got := etagMatches(c.ifMatch, c.etag); got != c.want {
+			t.Errorf("etagMatches(%q, %q) = %v; expected %v", c.ifMatch, c.etag, got, c.want)
+		}
+	}
+}
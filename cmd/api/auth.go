@@ -82,7 +82,7 @@ func (app *application) userRegisterHandler(res http.ResponseWriter, req *http.R
 	err = app.mailer.Send(mailer.UserActivationTemplate, user.Username, user.Email, vars)
 	if err != nil {
 		app.logger.Errorw("error sending welcome email", "error", err)
-		if err := app.store.Users.Delete(ctx, user.ID); err != nil {
+		if _, err := app.store.Users.Delete(ctx, user.ID); err != nil {
 			app.internalServerError(res, req, err)
 			return
 		}
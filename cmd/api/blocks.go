@@ -0,0 +1,72 @@
+package main
+
+import (
+	"Blog/internal/store"
+	"Blog/internal/store/paginate"
+	"context"
+	"errors"
+	"net/http"
+)
+
+var (
+	ErrSelfTarget = errors.New("you can't do that to yourself")
+	ErrBlocked    = errors.New("one of you has blocked the other")
+)
+
+func (app *application) blockUserHandler(res http.ResponseWriter, req *http.Request) {
+	app.writeUserToggle(res, req, app.store.Blocks.Block)
+}
+
+func (app *application) unblockUserHandler(res http.ResponseWriter, req *http.Request) {
+	app.writeUserToggle(res, req, app.store.Blocks.Unblock)
+}
+
+func (app *application) muteUserHandler(res http.ResponseWriter, req *http.Request) {
+	app.writeUserToggle(res, req, app.store.Blocks.Mute)
+}
+
+func (app *application) unmuteUserHandler(res http.ResponseWriter, req *http.Request) {
+	app.writeUserToggle(res, req, app.store.Blocks.Unmute)
+}
+
+// writeUserToggle applies toggle from the signed in user to the user in the path.
+func (app *application) writeUserToggle(res http.ResponseWriter, req *http.Request, toggle func(context.Context, int64, int64) error) {
+	user := getAuthUser(req)
+	target := getUserFromContext(req)
+	if user.ID == target.ID {
+		app.badRequestError(res, req, ErrSelfTarget)
+		return
+	}
+	app.writeToggleResult(res, req, toggle(req.Context(), user.ID, target.ID))
+}
+
+func (app *application) getBlockedUsersHandler(res http.ResponseWriter, req *http.Request) {
+	app.writeBlockList(res, req, app.store.Blocks.GetBlocked)
+}
+
+func (app *application) getMutedUsersHandler(res http.ResponseWriter, req *http.Request) {
+	app.writeBlockList(res, req, app.store.Blocks.GetMuted)
+}
+
+func (app *application) writeBlockList(res http.ResponseWriter, req *http.Request, list func(context.Context, int64, *paginate.PaginatedQuery) ([]store.User, error)) {
+	pq := &paginate.PaginatedQuery{}
+	pq.SetDefaults()
+	if err := pq.Parse(req); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	if err := validate.Struct(pq); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	user := getAuthUser(req)
+	users, err := list(req.Context(), user.ID, pq)
+	if err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+	if err := app.jsonResponse(res, http.StatusOK, users); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"Blog/internal/store"
+	"Blog/internal/store/paginate"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type readingListKey string
+
+const readingListCtx readingListKey = "readingList"
+
+type CreateReadingListPayload struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+func (app *application) getReadingListsHandler(res http.ResponseWriter, req *http.Request) {
+	user := getAuthUser(req)
+	lists, err := app.store.Bookmarks.GetLists(req.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+	if err := app.jsonResponse(res, http.StatusOK, lists); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+}
+
+func (app *application) createReadingListHandler(res http.ResponseWriter, req *http.Request) {
+	var payload CreateReadingListPayload
+	if err := readJSON(res, req, &payload); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	if err := validate.Struct(payload); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	user := getAuthUser(req)
+	list := &store.ReadingList{
+		UserID: user.ID,
+		Name:   payload.Name,
+	}
+	if err := app.store.Bookmarks.CreateList(req.Context(), list); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictError(res, req, err)
+		default:
+			app.internalServerError(res, req, err)
+		}
+		return
+	}
+	if err := app.jsonResponse(res, http.StatusCreated, list); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+}
+
+func (app *application) deleteReadingListHandler(res http.ResponseWriter, req *http.Request) {
+	list := getReadingListFromCtx(req)
+	if err := app.store.Bookmarks.DeleteList(req.Context(), list.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(res, req, err)
+		default:
+			app.internalServerError(res, req, err)
+		}
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) getReadingListPostsHandler(res http.ResponseWriter, req *http.Request) {
+	fq := &paginate.PostPaginateQuery{}
+	err := fq.Parse(req)
+	if err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	if err := validate.Struct(fq); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	ctx := req.Context()
+	list := getReadingListFromCtx(req)
+	user := getAuthUser(req)
+	posts, page, err := app.store.Bookmarks.GetPosts(ctx, list.ID, user.ID, fq)
+	if err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+	if err := app.attachReactions(ctx, user.ID, posts); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+	if err := app.jsonCursorResponse(res, req, http.StatusOK, posts, page); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+}
+
+func (app *application) addBookmarkHandler(res http.ResponseWriter, req *http.Request) {
+	list := getReadingListFromCtx(req)
+	postId, err := strconv.ParseInt(chi.URLParam(req, "postId"), 10, 64)
+	if err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	ctx := req.Context()
+	post, err := app.store.Posts.GetById(ctx, postId, list.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(res, req, err)
+		default:
+			app.internalServerError(res, req, err)
+		}
+		return
+	}
+	if post.Status != store.PostStatusPublished && post.UserId != list.UserID {
+		app.notFoundError(res, req, store.ErrNotFound)
+		return
+	}
+	app.writeToggleResult(res, req, app.store.Bookmarks.AddPost(ctx, list.ID, post.ID))
+}
+
+func (app *application) removeBookmarkHandler(res http.ResponseWriter, req *http.Request) {
+	list := getReadingListFromCtx(req)
+	postId, err := strconv.ParseInt(chi.URLParam(req, "postId"), 10, 64)
+	if err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	app.writeToggleResult(res, req, app.store.Bookmarks.RemovePost(req.Context(), list.ID, postId))
+}
+
+// readingListContextMiddleware loads the list, answering 404 for lists that
+// belong to someone else.
+func (app *application) readingListContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		listId, err := strconv.ParseInt(chi.URLParam(req, "listId"), 10, 64)
+		if err != nil {
+			app.badRequestError(res, req, err)
+			return
+		}
+		ctx := req.Context()
+		list, err := app.store.Bookmarks.GetListByID(ctx, listId)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundError(res, req, err)
+			default:
+				app.internalServerError(res, req, err)
+			}
+			return
+		}
+		if list.UserID != getAuthUser(req).ID {
+			app.notFoundError(res, req, store.ErrNotFound)
+			return
+		}
+		ctx = context.WithValue(ctx, readingListCtx, list)
+		next.ServeHTTP(res, req.WithContext(ctx))
+	})
+}
+
+func getReadingListFromCtx(req *http.Request) *store.ReadingList {
+	list, _ := req.Context().Value(readingListCtx).(*store.ReadingList)
+	return list
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"Blog/internal/store"
+	"Blog/internal/store/paginate"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type commentKey string
+
+const commentCtx commentKey = "comment"
+
+type UpdateCommentPayload struct {
+	Content string `json:"content" validate:"max=200,min=3"`
+}
+
+func (app *application) updateCommentHandler(res http.ResponseWriter, req *http.Request) {
+	comment := getCommentFromCtx(req)
+	var payload UpdateCommentPayload
+	if err := readJSON(res, req, &payload); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	if err := validate.Struct(payload); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	comment.Content = payload.Content
+	ctx := req.Context()
+	if err := app.store.Comments.Update(ctx, comment); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(res, req, err)
+		default:
+			app.internalServerError(res, req, err)
+		}
+		return
+	}
+	if err := app.jsonResponse(res, http.StatusOK, comment); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+}
+
+func (app *application) deleteCommentHandler(res http.ResponseWriter, req *http.Request) {
+	comment := getCommentFromCtx(req)
+	ctx := req.Context()
+	if err := app.store.Comments.Delete(ctx, comment.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(res, req, err)
+		default:
+			app.internalServerError(res, req, err)
+		}
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) getPostCommentsHandler(res http.ResponseWriter, req *http.Request) {
+	post := getPostFromCtx(req)
+	cq := &paginate.CommentPaginateQuery{}
+	if err := cq.Parse(req); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	if err := validate.Struct(cq); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	user := getAuthUser(req)
+	comments, err := app.store.Comments.ListByPostID(req.Context(), post.ID, user.ID, cq)
+	if err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+	if err := app.jsonResponse(res, http.StatusOK, comments); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+}
+
+func (app *application) getCommentTreeHandler(res http.ResponseWriter, req *http.Request) {
+	post := getPostFromCtx(req)
+	user := getAuthUser(req)
+	comments, err := app.store.Comments.GetByPostID(req.Context(), post.ID, user.ID)
+	if err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+	if err := app.jsonResponse(res, http.StatusOK, store.BuildCommentTree(comments)); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+}
+
+func (app *application) getCommentRepliesHandler(res http.ResponseWriter, req *http.Request) {
+	comment := getCommentFromCtx(req)
+	pq := &paginate.PaginatedQuery{}
+	pq.SetDefaults()
+	if err := pq.Parse(req); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	if err := validate.Struct(pq); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	user := getAuthUser(req)
+	replies, err := app.store.Comments.GetReplies(req.Context(), comment.ID, user.ID, pq)
+	if err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+	if err := app.jsonResponse(res, http.StatusOK, replies); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+}
+
+func (app *application) commentsContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		commentId, err := strconv.ParseInt(chi.URLParam(req, "commentId"), 10, 64)
+		if err != nil {
+			app.badRequestError(res, req, err)
+			return
+		}
+		ctx := req.Context()
+		comment, err := app.store.Comments.GetByID(ctx, commentId, getAuthUser(req).ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundError(res, req, err)
+			default:
+				app.internalServerError(res, req, err)
+			}
+			return
+		}
+		if comment.PostID != getPostFromCtx(req).ID {
+			app.notFoundError(res, req, store.ErrNotFound)
+			return
+		}
+		ctx = context.WithValue(ctx, commentCtx, comment)
+		next.ServeHTTP(res, req.WithContext(ctx))
+	})
+}
+
+func getCommentFromCtx(req *http.Request) *store.Comment {
+	comment, _ := req.Context().Value(commentCtx).(*store.Comment)
+	return comment
+}
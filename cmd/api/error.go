@@ -1,6 +1,7 @@
 package main
 
 import (
+	"Blog/internal/store"
 	"net/http"
 )
 
@@ -48,3 +49,30 @@ func (app *application) rateLimitExceededResponse(res http.ResponseWriter, req *
 	res.Header().Set("Retry-After", retryAfter)
 	writeJSONError(res, http.StatusTooManyRequests, "rate limit exceeded please try after : "+retryAfter)
 }
+
+func (app *application) preconditionRequiredError(res http.ResponseWriter, req *http.Request, err error) {
+	app.logger.Warnw("precondition required", err, "path", req.URL.Path, "method", req.Method, "message", err.Error())
+	writeJSONError(res, http.StatusPreconditionRequired, "precondition required")
+}
+
+// preconditionFailedResponse sends the current post so the client can merge
+// its edit and retry with the new ETag.
+func (app *application) preconditionFailedResponse(res http.ResponseWriter, req *http.Request, current *store.Post) {
+	app.logger.Warnw("precondition failed", "path", req.URL.Path, "method", req.Method, "version", current.Version)
+	type preconditionJSON struct {
+		Error string      `json:"error"`
+		Data  *store.Post `json:"data"`
+	}
+	res.Header().Set("ETag", postETag(current))
+	writeJSON(res, http.StatusPreconditionFailed, &preconditionJSON{Error: "post was modified", Data: current})
+}
+
+func (app *application) payloadTooLargeError(res http.ResponseWriter, req *http.Request, err error) {
+	app.logger.Warnw("payload too large", err, "path", req.URL.Path, "method", req.Method, "message", err.Error())
+	writeJSONError(res, http.StatusRequestEntityTooLarge, "file is too large")
+}
+
+func (app *application) unsupportedMediaTypeError(res http.ResponseWriter, req *http.Request, err error) {
+	app.logger.Warnw("unsupported media type", err, "path", req.URL.Path, "method", req.Method, "message", err.Error())
+	writeJSONError(res, http.StatusUnsupportedMediaType, "unsupported file type")
+}
@@ -24,7 +24,15 @@ func (app *application) getUserFeedHandler(res http.ResponseWriter, req *http.Re
 	}
 	ctx := req.Context()
 	user := getAuthUser(req)
-	feed, err := app.store.Posts.GetUserFeed(ctx, user.ID, fq)
+	var (
+		feed []store.PostWithMetaData
+		page paginate.CursorPage
+	)
+	if fq.Mode == "ranked" {
+		feed, page, err = app.store.Posts.GetRankedFeed(ctx, user.ID, fq, app.config.ranking)
+	} else {
+		feed, page, err = app.store.Posts.GetUserFeed(ctx, user.ID, fq)
+	}
 	if err != nil {
 		switch err {
 		case store.ErrNotFound:
@@ -35,7 +43,60 @@ func (app *application) getUserFeedHandler(res http.ResponseWriter, req *http.Re
 			return
 		}
 	}
-	if err := app.jsonResponse(res, http.StatusOK, feed); err != nil {
+	if err := app.attachReactions(ctx, user.ID, feed); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+	// the score breakdown is only shown when debugging the ranking
+	if !fq.Debug {
+		for i := range feed {
+			feed[i].Score = nil
+		}
+	}
+	if err := app.jsonCursorResponse(res, req, http.StatusOK, feed, page); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+}
+
+// getExploreFeedHandler lists published posts from everyone, signed in or not.
+func (app *application) getExploreFeedHandler(res http.ResponseWriter, req *http.Request) {
+	app.writePublishedPosts(res, req, 0, "")
+}
+
+func (app *application) getUserPostsHandler(res http.ResponseWriter, req *http.Request) {
+	author := getUserFromContext(req)
+	app.writePublishedPosts(res, req, author.ID, "")
+}
+
+// writePublishedPosts responds with a page of published posts, narrowed to
+// authorID and tag when they are set.
+func (app *application) writePublishedPosts(res http.ResponseWriter, req *http.Request, authorID int64, tag string) {
+	fq := &paginate.PostPaginateQuery{}
+	err := fq.Parse(req)
+	if err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	if tag != "" {
+		fq.Tags = []string{tag}
+	}
+	if err := validate.Struct(fq); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	ctx := req.Context()
+	viewer := getViewerID(req)
+	posts, page, err := app.store.Posts.GetPublished(ctx, viewer, authorID, fq)
+	if err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+	if err := app.attachReactions(ctx, viewer, posts); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+	if err := app.jsonCursorResponse(res, req, http.StatusOK, posts, page); err != nil {
 		app.internalServerError(res, req, err)
 		return
 	}
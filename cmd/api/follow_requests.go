@@ -0,0 +1,90 @@
+package main
+
+import (
+	"Blog/internal/store"
+	"Blog/internal/store/paginate"
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type FollowStatus struct {
+	Status string `json:"status"`
+}
+
+type PrivacyPayload struct {
+	IsPrivate *bool `json:"is_private" validate:"required"`
+}
+
+// updatePrivacyHandler makes the signed in account private or public. Going
+// public accepts all pending follow requests.
+func (app *application) updatePrivacyHandler(res http.ResponseWriter, req *http.Request) {
+	var payload PrivacyPayload
+	if err := readJSON(res, req, &payload); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	if err := validate.Struct(payload); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	user := getAuthUser(req)
+	if err := app.store.Users.SetPrivate(req.Context(), user.ID, *payload.IsPrivate); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) getIncomingRequestsHandler(res http.ResponseWriter, req *http.Request) {
+	app.writeFollowRequests(res, req, app.store.Followers.GetIncomingRequests)
+}
+
+func (app *application) getOutgoingRequestsHandler(res http.ResponseWriter, req *http.Request) {
+	app.writeFollowRequests(res, req, app.store.Followers.GetOutgoingRequests)
+}
+
+func (app *application) writeFollowRequests(res http.ResponseWriter, req *http.Request, list func(context.Context, int64, *paginate.PaginatedQuery) ([]store.FollowRequest, error)) {
+	pq := &paginate.PaginatedQuery{}
+	pq.SetDefaults()
+	if err := pq.Parse(req); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	if err := validate.Struct(pq); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	user := getAuthUser(req)
+	requests, err := list(req.Context(), user.ID, pq)
+	if err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+	if err := app.jsonResponse(res, http.StatusOK, requests); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+}
+
+func (app *application) approveRequestHandler(res http.ResponseWriter, req *http.Request) {
+	app.writeRequestDecision(res, req, app.store.Followers.ApproveRequest)
+}
+
+func (app *application) rejectRequestHandler(res http.ResponseWriter, req *http.Request) {
+	app.writeRequestDecision(res, req, app.store.Followers.RejectRequest)
+}
+
+// writeRequestDecision applies decide to the request the user in the path
+// sent to the signed in user.
+func (app *application) writeRequestDecision(res http.ResponseWriter, req *http.Request, decide func(context.Context, int64, int64) error) {
+	requesterId, err := strconv.ParseInt(chi.URLParam(req, "requesterId"), 10, 64)
+	if err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	user := getAuthUser(req)
+	app.writeToggleResult(res, req, decide(req.Context(), user.ID, requesterId))
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"Blog/internal/store/paginate"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -40,3 +43,35 @@ func (app *application) jsonResponse(res http.ResponseWriter, status int, data a
 	}
 	return writeJSON(res, status, &resJSON{Data: data})
 }
+
+// jsonCursorResponse wraps data in the usual envelope along with the cursors
+// of the neighbouring pages, and mirrors them as RFC 5988 Link headers.
+func (app *application) jsonCursorResponse(res http.ResponseWriter, req *http.Request, status int, data any, page paginate.CursorPage) error {
+	links := []string{}
+	if page.NextCursor != "" {
+		links = append(links, pageLink(req, "after", page.NextCursor, "next"))
+	}
+	if page.PrevCursor != "" {
+		links = append(links, pageLink(req, "before", page.PrevCursor, "prev"))
+	}
+	if len(links) > 0 {
+		res.Header().Set("Link", strings.Join(links, ", "))
+	}
+	type resJSON struct {
+		Data       any    `json:"data"`
+		NextCursor string `json:"next_cursor,omitempty"`
+		PrevCursor string `json:"prev_cursor,omitempty"`
+	}
+	return writeJSON(res, status, &resJSON{Data: data, NextCursor: page.NextCursor, PrevCursor: page.PrevCursor})
+}
+
+func pageLink(req *http.Request, param string, cursor string, rel string) string {
+	u := *req.URL
+	qs := u.Query()
+	qs.Del("after")
+	qs.Del("before")
+	qs.Del("offset")
+	qs.Set(param, cursor)
+	u.RawQuery = qs.Encode()
+	return fmt.Sprintf(`<%s>; rel="%s"`, u.RequestURI(), rel)
+}
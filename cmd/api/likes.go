@@ -0,0 +1,50 @@
+package main
+
+import (
+	"Blog/internal/store"
+	"errors"
+	"net/http"
+)
+
+func (app *application) likePostHandler(res http.ResponseWriter, req *http.Request) {
+	post := getPostFromCtx(req)
+	user := getAuthUser(req)
+	app.writeToggleResult(res, req, app.store.Likes.LikePost(req.Context(), user.ID, post.ID))
+}
+
+func (app *application) unlikePostHandler(res http.ResponseWriter, req *http.Request) {
+	post := getPostFromCtx(req)
+	user := getAuthUser(req)
+	app.writeToggleResult(res, req, app.store.Likes.UnlikePost(req.Context(), user.ID, post.ID))
+}
+
+func (app *application) likeCommentHandler(res http.ResponseWriter, req *http.Request) {
+	comment := getCommentFromCtx(req)
+	if comment.Deleted {
+		app.notFoundError(res, req, store.ErrNotFound)
+		return
+	}
+	user := getAuthUser(req)
+	app.writeToggleResult(res, req, app.store.Likes.LikeComment(req.Context(), user.ID, comment.ID))
+}
+
+func (app *application) unlikeCommentHandler(res http.ResponseWriter, req *http.Request) {
+	comment := getCommentFromCtx(req)
+	user := getAuthUser(req)
+	app.writeToggleResult(res, req, app.store.Likes.UnlikeComment(req.Context(), user.ID, comment.ID))
+}
+
+func (app *application) writeToggleResult(res http.ResponseWriter, req *http.Request, err error) {
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictError(res, req, err)
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(res, req, err)
+		default:
+			app.internalServerError(res, req, err)
+		}
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"Blog/internal/auth"
+	"Blog/internal/blob"
 	"Blog/internal/db"
 	"Blog/internal/env"
 	"Blog/internal/mailer"
@@ -46,10 +47,14 @@ func main() {
 		},
 		env:         env.GetString("ENV", "development"),
 		frontendURL: env.GetString("FRONTEND_URL", "http://localhost:3001/"),
+		apiURL:      env.GetString("API_URL", "http://localhost:3002"),
 		mail: mailConfig{
-			exp:       time.Minute * 50,
-			fromEmail: env.GetString("FROM_EMAIL", "support@bloggerspot.xyz"),
-			apiKey:    env.GetString("EMAIL_API_KEY", "re_oJ5dfMhR_6MSRJ8omE1MYVLEcrKpToQDS"),
+			exp:         time.Minute * 50,
+			resetExp:    time.Minute * time.Duration(env.GetInt("PASSWORD_RESET_EXPIRY_MINUTES", 30)),
+			resetLimit:  env.GetInt("PASSWORD_RESET_LIMIT", 3),
+			resetWindow: env.GetDuration("PASSWORD_RESET_WINDOW", time.Hour),
+			fromEmail:   env.GetString("FROM_EMAIL", "support@bloggerspot.xyz"),
+			apiKey:      env.GetString("EMAIL_API_KEY", "re_oJ5dfMhR_6MSRJ8omE1MYVLEcrKpToQDS"),
 		},
 		auth: authConfig{
 			basic: basicConfig{
@@ -67,6 +72,49 @@ func main() {
 			TimeFrame:       time.Second * 5,
 			Enabled:         env.GetBool("RATE_LIMITER_ENABLED", true),
 		},
+		publisher: publisherConfig{
+			enabled:   env.GetBool("PUBLISHER_ENABLED", true),
+			interval:  env.GetDuration("PUBLISHER_INTERVAL", time.Second*30),
+			batchSize: env.GetInt("PUBLISHER_BATCH_SIZE", 50),
+		},
+		comments: commentConfig{
+			maxDepth:     env.GetInt("COMMENTS_MAX_DEPTH", 5),
+			previewCount: env.GetInt("COMMENTS_PREVIEW_COUNT", 3),
+		},
+		ranking: store.RankingConfig{
+			RecencyWeight:  env.GetFloat("RANKING_RECENCY_WEIGHT", 3),
+			CommentWeight:  env.GetFloat("RANKING_COMMENT_WEIGHT", 1),
+			LikeWeight:     env.GetFloat("RANKING_LIKE_WEIGHT", 0.5),
+			AffinityWeight: env.GetFloat("RANKING_AFFINITY_WEIGHT", 1),
+			HalfLife:       env.GetDuration("RANKING_HALF_LIFE", time.Hour*24),
+			Window:         env.GetDuration("RANKING_WINDOW", time.Hour*24*14),
+		},
+		profile: profileConfig{
+			usernameCooldown:    time.Hour * 24 * time.Duration(env.GetInt("USERNAME_CHANGE_COOLDOWN_DAYS", 30)),
+			usernameReservation: time.Hour * 24 * time.Duration(env.GetInt("USERNAME_RESERVATION_DAYS", 90)),
+		},
+		blob: blobConfig{
+			backend:   env.GetString("BLOB_BACKEND", "local"),
+			localDir:  env.GetString("BLOB_LOCAL_DIR", "./uploads"),
+			publicURL: env.GetString("BLOB_PUBLIC_URL", "http://localhost:3002/v1/media"),
+			s3: blob.S3Config{
+				Endpoint:  env.GetString("S3_ENDPOINT", "http://localhost:9000"),
+				Region:    env.GetString("S3_REGION", "us-east-1"),
+				Bucket:    env.GetString("S3_BUCKET", "bloggerspot"),
+				AccessKey: env.GetString("S3_ACCESS_KEY", "minioadmin"),
+				SecretKey: env.GetString("S3_SECRET_KEY", "minioadmin"),
+				PathStyle: env.GetBool("S3_PATH_STYLE", true),
+				PublicURL: env.GetString("S3_PUBLIC_URL", ""),
+			},
+		},
+		upload: uploadConfig{
+			maxAvatarSize: int64(env.GetInt("UPLOAD_MAX_AVATAR_BYTES", 2<<20)),
+			maxImageSize:  int64(env.GetInt("UPLOAD_MAX_IMAGE_BYTES", 8<<20)),
+			thumbnailSize: env.GetInt("UPLOAD_THUMBNAIL_SIZE", 320),
+		},
+		reactions: reactionConfig{
+			allowed: parseReactions(env.GetString("REACTIONS_ALLOWED", "👍,❤️,😂,🎉")),
+		},
 	}
 	// JWT
 	jwtAuth := auth.NewJWT(cfg.auth.token.secret, cfg.auth.token.iss, cfg.auth.token.iss)
@@ -78,6 +126,7 @@ func main() {
 	defer logger.Sync()
 	// Rate limiter
 	rateLimiter := ratelimiter.NewFixedWindowRateLimiter(cfg.rateLimiter.RequestPerFrame, cfg.rateLimiter.TimeFrame)
+	resetLimiter := ratelimiter.NewFixedWindowRateLimiter(cfg.mail.resetLimit, cfg.mail.resetWindow)
 	// Database
 	db, err := db.NewDB(cfg.db.addr, cfg.db.maxOpenConns, cfg.db.maxIdleConns, cfg.db.maxIdleTime, logger)
 	if err != nil {
@@ -85,13 +134,26 @@ func main() {
 	}
 	defer db.Close()
 	store := store.NewPostgresStore(db)
+	// Blob store
+	var blobs blob.BlobStore
+	switch cfg.blob.backend {
+	case "s3":
+		blobs, err = blob.NewS3Store(cfg.blob.s3)
+	default:
+		blobs, err = blob.NewLocalStore(cfg.blob.localDir, cfg.blob.publicURL)
+	}
+	if err != nil {
+		logger.Fatal("Blob store setup failed", err)
+	}
 	app := &application{
-		config:      cfg,
-		store:       store,
-		logger:      logger,
-		mailer:      mailer,
-		auth:        *jwtAuth,
-		rateLimiter: rateLimiter,
+		config:       cfg,
+		store:        store,
+		logger:       logger,
+		mailer:       mailer,
+		auth:         *jwtAuth,
+		rateLimiter:  rateLimiter,
+		resetLimiter: resetLimiter,
+		blobs:        blobs,
 	}
 	logger.Info("Server is starting on %v\n", cfg.addr)
 	mux := app.mount()
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"Blog/internal/mailer"
+	"Blog/internal/store"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ForgotPasswordPayload struct {
+	Email string `json:"email" validate:"required,email,max=200"`
+}
+
+type ResetPasswordPayload struct {
+	Token    string `json:"token" validate:"required,max=100"`
+	Password string `json:"password" validate:"required,min=3,max=72"`
+}
+
+// forgotPasswordHandler mails a reset link. The token is created and the
+// mail sent in the background, so the response is the same, and takes the
+// same time, whether or not the email belongs to an account. Requests are
+// throttled per email address so the endpoint can't be used to flood an inbox.
+func (app *application) forgotPasswordHandler(res http.ResponseWriter, req *http.Request) {
+	var payload ForgotPasswordPayload
+	if err := readJSON(res, req, &payload); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	if err := validate.Struct(payload); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(payload.Email))
+	if allow, retryAfter := app.resetLimiter.Allow(email); !allow {
+		app.rateLimitExceededResponse(res, req, strconv.Itoa(int(retryAfter.Seconds())))
+		return
+	}
+	app.background(func() {
+		app.sendPasswordReset(payload.Email)
+	})
+	res.WriteHeader(http.StatusAccepted)
+}
+
+func (app *application) sendPasswordReset(email string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	// only the hash is stored, the plain token goes out in the email
+	token := uuid.New().String()
+	hash := sha256.Sum256([]byte(token))
+	hashedToken := hex.EncodeToString(hash[:])
+	exp := app.config.mail.resetExp
+	user, err := app.store.Users.CreatePasswordReset(ctx, email, hashedToken, exp)
+	if err != nil {
+		// no active account with this email, nothing to send
+		if !errors.Is(err, store.ErrNotFound) {
+			app.logger.Errorw("error creating password reset", "error", err)
+		}
+		return
+	}
+	vars := struct {
+		Username  string
+		ResetURL  string
+		ExpiresIn string
+	}{
+		Username:  user.Username,
+		ResetURL:  fmt.Sprintf("%s/reset-password/%s", app.config.frontendURL, token),
+		ExpiresIn: fmt.Sprintf("%.0f minutes", exp.Minutes()),
+	}
+	if err := app.mailer.Send(mailer.PasswordResetTemplate, user.Username, user.Email, vars); err != nil {
+		app.logger.Errorw("error sending password reset email", "user_id", user.ID, "error", err)
+	}
+}
+
+func (app *application) resetPasswordHandler(res http.ResponseWriter, req *http.Request) {
+	var payload ResetPasswordPayload
+	if err := readJSON(res, req, &payload); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	if err := validate.Struct(payload); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	var password store.PasswordType
+	if err := password.Set(payload.Password); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+	if err := app.store.Users.ResetPassword(req.Context(), payload.Token, &password); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(res, req, err)
+		default:
+			app.internalServerError(res, req, err)
+		}
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
@@ -2,11 +2,15 @@ package main
 
 import (
 	"Blog/internal/store"
+	"Blog/internal/store/paginate"
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -16,40 +20,84 @@ type postKey string
 const postCtx postKey = "post"
 
 type CreatePostPayload struct {
-	Content string   `json:"content" validate:"omitempty,max=1000"`
-	Title   string   `json:"title" validate:"omitempty,max=50"`
-	Tags    []string `json:"tags"`
+	Content    string     `json:"content" validate:"omitempty,max=1000"`
+	Title      string     `json:"title" validate:"omitempty,max=50"`
+	Tags       []string   `json:"tags"`
+	Status     string     `json:"status" validate:"omitempty,oneof=draft published"`
+	Visibility string     `json:"visibility" validate:"omitempty,oneof=public followers unlisted private"`
+	PublishAt  *time.Time `json:"publish_at"`
+}
+
+type SchedulePostPayload struct {
+	PublishAt *time.Time `json:"publish_at"`
 }
 
 type CreateCommentPaylaod struct {
-	Content string `json:"content" validate:"max=200,min=3"`
+	Content  string `json:"content" validate:"max=200,min=3"`
+	ParentID *int64 `json:"parent_id" validate:"omitempty,gte=1"`
 }
 type DeletedPostPayload struct {
 	ID int64 `json:"content"`
 }
 
 type UpdatePostPayload struct {
-	Title   *string `json:"title" validate:"omitempty,max=100"`
-	Content *string `json:"content" validate:"omitempty,max=1000"`
+	Title      *string `json:"title" validate:"omitempty,max=100"`
+	Content    *string `json:"content" validate:"omitempty,max=1000"`
+	Visibility *string `json:"visibility" validate:"omitempty,oneof=public followers unlisted private"`
 }
 
+// getPostHanlder returns the post with its comment count and the newest few
+// comments, the rest are listed through /comments.
 func (app *application) getPostHanlder(res http.ResponseWriter, req *http.Request) {
 	post := getPostFromCtx(req)
-	comments, err := app.store.Comments.GetByPostID(req.Context(), post.ID)
+	ctx := req.Context()
+	preview := &paginate.CommentPaginateQuery{}
+	preview.SetDefaults()
+	preview.Limit = app.config.comments.previewCount
+	user := getAuthUser(req)
+	comments, err := app.store.Comments.ListByPostID(ctx, post.ID, user.ID, preview)
+	if err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+	post.Liked, err = app.store.Likes.HasLikedPost(ctx, user.ID, post.ID)
 	if err != nil {
 		app.internalServerError(res, req, err)
 		return
 	}
 	post.Comments = comments
+	count, err := app.store.Comments.CountByPostID(ctx, post.ID)
+	if err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
 
-	if err := app.jsonResponse(res, http.StatusOK, post); err != nil {
+	withMeta := []store.PostWithMetaData{{Post: *post, CommentCount: count}}
+	if err := app.attachReactions(ctx, user.ID, withMeta); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+
+	res.Header().Set("ETag", postETag(post))
+	if err := app.jsonResponse(res, http.StatusOK, withMeta[0]); err != nil {
 		app.internalServerError(res, req, err)
 		return
 	}
 }
 
+// updatePostHandler requires an If-Match header carrying the ETag of the
+// version being edited, so concurrent editors can't overwrite each other.
 func (app *application) updatePostHandler(res http.ResponseWriter, req *http.Request) {
 	post := getPostFromCtx(req)
+	ifMatch := req.Header.Get("If-Match")
+	if ifMatch == "" {
+		app.preconditionRequiredError(res, req, errors.New("If-Match header missing"))
+		return
+	}
+	if !etagMatches(ifMatch, postETag(post)) {
+		app.preconditionFailedResponse(res, req, post)
+		return
+	}
 	var payload UpdatePostPayload
 	if err := readJSON(res, req, &payload); err != nil {
 		app.badRequestError(res, req, err)
@@ -66,21 +114,36 @@ func (app *application) updatePostHandler(res http.ResponseWriter, req *http.Req
 	if payload.Title != nil {
 		post.Title = *payload.Title
 	}
+	if payload.Visibility != nil {
+		post.Visibility = *payload.Visibility
+	}
 
 	ctx := req.Context()
 
 	err := app.store.Posts.Update(ctx, post)
 	if err != nil {
 		switch {
-		case errors.Is(err, store.ErrNotFound):
-			app.notFoundError(res, req, err)
+		case errors.Is(err, store.ErrVersionMismatch):
+			// another editor won the race, send back what they saved
+			current, err := app.store.Posts.GetByIdUnchecked(ctx, post.ID)
+			if err != nil {
+				switch {
+				case errors.Is(err, store.ErrNotFound):
+					app.notFoundError(res, req, err)
+				default:
+					app.internalServerError(res, req, err)
+				}
+				return
+			}
+			app.preconditionFailedResponse(res, req, current)
 		default:
-			app.badRequestError(res, req, err)
+			app.internalServerError(res, req, err)
 		}
 		return
 	}
-	if err := app.jsonResponse(res, http.StatusOK, payload); err != nil {
-		app.badRequestError(res, req, err)
+	res.Header().Set("ETag", postETag(post))
+	if err := app.jsonResponse(res, http.StatusOK, post); err != nil {
+		app.internalServerError(res, req, err)
 		return
 	}
 }
@@ -91,7 +154,7 @@ func (app *application) deletePostHandler(res http.ResponseWriter, req *http.Req
 
 	ctx := req.Context()
 
-	err := app.store.Posts.Delete(ctx, post.ID)
+	images, err := app.store.Posts.Delete(ctx, post.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -102,6 +165,9 @@ func (app *application) deletePostHandler(res http.ResponseWriter, req *http.Req
 			return
 		}
 	}
+	for i := range images {
+		app.removeMedia(ctx, &images[i])
+	}
 	res.WriteHeader(http.StatusNoContent)
 }
 
@@ -116,12 +182,23 @@ func (app *application) createPostHandler(res http.ResponseWriter, req *http.Req
 		app.badRequestError(res, req, err)
 		return
 	}
+	if payload.PublishAt != nil {
+		// scheduled posts stay drafts until the publisher picks them up
+		if payload.Status == store.PostStatusPublished || !payload.PublishAt.After(time.Now()) {
+			app.badRequestError(res, req, errors.New("publish_at must be in the future and post must be a draft"))
+			return
+		}
+		payload.Status = store.PostStatusDraft
+	}
 	user := getAuthUser(req)
 	post := &store.Post{
-		UserId:  user.ID,
-		Title:   payload.Title,
-		Content: payload.Content,
-		Tags:    payload.Tags,
+		UserId:     user.ID,
+		Title:      payload.Title,
+		Content:    payload.Content,
+		Tags:       payload.Tags,
+		Status:     payload.Status,
+		Visibility: payload.Visibility,
+		PublishAt:  payload.PublishAt,
 	}
 	ctx := req.Context()
 	err = app.store.Posts.Create(ctx, post)
@@ -135,6 +212,91 @@ func (app *application) createPostHandler(res http.ResponseWriter, req *http.Req
 	}
 }
 
+func (app *application) publishPostHandler(res http.ResponseWriter, req *http.Request) {
+	app.changePostStatus(res, req, store.PostStatusPublished)
+}
+
+func (app *application) unpublishPostHandler(res http.ResponseWriter, req *http.Request) {
+	app.changePostStatus(res, req, store.PostStatusDraft)
+}
+
+func (app *application) archivePostHandler(res http.ResponseWriter, req *http.Request) {
+	app.changePostStatus(res, req, store.PostStatusArchived)
+}
+
+func (app *application) changePostStatus(res http.ResponseWriter, req *http.Request, status string) {
+	post := getPostFromCtx(req)
+	post.Status = status
+	ctx := req.Context()
+	err := app.store.Posts.UpdateStatus(ctx, post)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictError(res, req, err)
+		default:
+			app.internalServerError(res, req, err)
+		}
+		return
+	}
+	if err := app.jsonResponse(res, http.StatusOK, post); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+}
+
+func (app *application) schedulePostHandler(res http.ResponseWriter, req *http.Request) {
+	post := getPostFromCtx(req)
+	var payload SchedulePostPayload
+	if err := readJSON(res, req, &payload); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	if payload.PublishAt != nil && !payload.PublishAt.After(time.Now()) {
+		app.badRequestError(res, req, errors.New("publish_at must be in the future"))
+		return
+	}
+	post.PublishAt = payload.PublishAt
+	ctx := req.Context()
+	err := app.store.Posts.Schedule(ctx, post)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictError(res, req, err)
+		default:
+			app.internalServerError(res, req, err)
+		}
+		return
+	}
+	if err := app.jsonResponse(res, http.StatusOK, post); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+}
+
+func (app *application) getUserDraftsHandler(res http.ResponseWriter, req *http.Request) {
+	fq := &paginate.PostPaginateQuery{}
+	err := fq.Parse(req)
+	if err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	if err := validate.Struct(fq); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	ctx := req.Context()
+	user := getAuthUser(req)
+	drafts, err := app.store.Posts.GetDrafts(ctx, user.ID, fq)
+	if err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+	if err := app.jsonResponse(res, http.StatusOK, drafts); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+}
+
 func (app *application) postCommentHandler(res http.ResponseWriter, req *http.Request) {
 	post := getPostFromCtx(req)
 	var payload CreateCommentPaylaod
@@ -152,9 +314,36 @@ func (app *application) postCommentHandler(res http.ResponseWriter, req *http.Re
 	comment.UserID = user.ID
 	comment.PostID = post.ID
 	ctx := req.Context()
+	if payload.ParentID != nil {
+		parent, err := app.store.Comments.GetByID(ctx, *payload.ParentID, user.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundError(res, req, err)
+			default:
+				app.internalServerError(res, req, err)
+			}
+			return
+		}
+		if parent.PostID != post.ID || parent.Deleted {
+			app.badRequestError(res, req, errors.New("parent comment is not open for replies on this post"))
+			return
+		}
+		if parent.Depth+1 > app.config.comments.maxDepth {
+			app.badRequestError(res, req, errors.New("maximum reply depth reached"))
+			return
+		}
+		comment.ParentID = &parent.ID
+		comment.Depth = parent.Depth + 1
+	}
 	err := app.store.Comments.Create(ctx, &comment)
 	if err != nil {
-		app.internalServerError(res, req, err)
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(res, req, err)
+		default:
+			app.internalServerError(res, req, err)
+		}
 		return
 	}
 	if err := app.jsonResponse(res, http.StatusOK, comment); err != nil {
@@ -165,28 +354,65 @@ func (app *application) postCommentHandler(res http.ResponseWriter, req *http.Re
 
 func (app *application) postsContextMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
-		postId, err := strconv.ParseInt(chi.URLParam(req, "postId"), 10, 64)
-		if err != nil {
-			app.internalServerError(res, req, err)
-			return
-		}
-
-		ctx := req.Context()
-		post, err := app.store.Posts.GetById(ctx, postId)
+		post, err := app.loadPost(req, false)
 		if err != nil {
-			switch {
-			case errors.Is(err, store.ErrNotFound):
-				app.notFoundError(res, req, err)
-			default:
-				app.internalServerError(res, req, err)
-			}
+			app.writeLoadPostError(res, req, err)
 			return
 		}
-		ctx = context.WithValue(ctx, postCtx, post)
+		ctx := context.WithValue(req.Context(), postCtx, post)
 		next.ServeHTTP(res, req.WithContext(ctx))
 	})
 }
 
+// loadPost fetches the post named in the URL. Unless moderate is set it has
+// to be visible to the signed in user, and only its author sees it before
+// it is published.
+func (app *application) loadPost(req *http.Request, moderate bool) (*store.Post, error) {
+	postId, err := strconv.ParseInt(chi.URLParam(req, "postId"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	ctx := req.Context()
+	if moderate {
+		return app.store.Posts.GetByIdUnchecked(ctx, postId)
+	}
+	user := getAuthUser(req)
+	post, err := app.store.Posts.GetById(ctx, postId, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if post.Status != store.PostStatusPublished && post.UserId != user.ID {
+		return nil, store.ErrNotFound
+	}
+	return post, nil
+}
+
+func (app *application) writeLoadPostError(res http.ResponseWriter, req *http.Request, err error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		app.notFoundError(res, req, err)
+	default:
+		app.internalServerError(res, req, err)
+	}
+}
+
+func postETag(post *store.Post) string {
+	return fmt.Sprintf(`"%d"`, post.Version)
+}
+
+// etagMatches reports whether an If-Match header value matches etag. Weak
+// tags count too, proxies are free to weaken the ETag we sent, and * matches
+// any version.
+func etagMatches(ifMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
 func getPostFromCtx(req *http.Request) *store.Post {
 	post, _ := req.Context().Value(postCtx).(*store.Post)
 	return post
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"Blog/internal/store"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type UpdateProfilePayload struct {
+	Username    *string `json:"username" validate:"omitempty,min=1,max=50"`
+	DisplayName *string `json:"display_name" validate:"omitempty,max=50"`
+	Bio         *string `json:"bio" validate:"omitempty,max=300"`
+	Website     *string `json:"website" validate:"omitempty,max=200,eq=|http_url"`
+	Location    *string `json:"location" validate:"omitempty,max=100"`
+}
+
+// updateProfileHandler edits the signed in user. Fields left out of the body
+// keep their value, an empty string clears them.
+func (app *application) updateProfileHandler(res http.ResponseWriter, req *http.Request) {
+	var payload UpdateProfilePayload
+	if err := readJSON(res, req, &payload); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	if err := validate.Struct(payload); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	user := *getAuthUser(req)
+	if payload.Username != nil {
+		user.Username = *payload.Username
+	}
+	if payload.DisplayName != nil {
+		user.DisplayName = *payload.DisplayName
+	}
+	if payload.Bio != nil {
+		user.Bio = *payload.Bio
+	}
+	if payload.Website != nil {
+		user.Website = *payload.Website
+	}
+	if payload.Location != nil {
+		user.Location = *payload.Location
+	}
+	cfg := app.config.profile
+	err := app.store.Users.UpdateProfile(req.Context(), &user, cfg.usernameCooldown, cfg.usernameReservation)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrUsernameTooSoon):
+			retryAfter := cfg.usernameCooldown
+			if changedAt := getAuthUser(req).UsernameChangedAt; changedAt != nil {
+				retryAfter = time.Until(changedAt.Add(cfg.usernameCooldown))
+			}
+			app.rateLimitExceededResponse(res, req, strconv.Itoa(int(retryAfter.Seconds())))
+		case errors.Is(err, store.ErrDuplicateUsername):
+			app.conflictError(res, req, err)
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(res, req, err)
+		default:
+			app.internalServerError(res, req, err)
+		}
+		return
+	}
+	if err := app.jsonResponse(res, http.StatusOK, user); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+}
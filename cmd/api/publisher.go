@@ -0,0 +1,77 @@
+package main
+
+import (
+	"Blog/internal/mailer"
+	"Blog/internal/store"
+	"context"
+	"fmt"
+	"time"
+)
+
+type publisherConfig struct {
+	enabled   bool
+	interval  time.Duration
+	batchSize int
+}
+
+// runScheduledPublisher publishes due drafts every interval until ctx is cancelled.
+func (app *application) runScheduledPublisher(ctx context.Context) {
+	ticker := time.NewTicker(app.config.publisher.interval)
+	defer ticker.Stop()
+	app.logger.Infow("scheduled publisher has started", "interval", app.config.publisher.interval.String())
+	for {
+		select {
+		case <-ctx.Done():
+			app.logger.Infow("scheduled publisher has stopped")
+			return
+		case <-ticker.C:
+			app.publishDuePosts(ctx)
+		}
+	}
+}
+
+func (app *application) publishDuePosts(ctx context.Context) {
+	posts, err := app.store.Posts.PublishDue(ctx, app.config.publisher.batchSize)
+	if err != nil {
+		app.logger.Errorw("error publishing scheduled posts", "error", err)
+		return
+	}
+	for _, post := range posts {
+		app.logger.Infow("scheduled post published", "post_id", post.ID)
+		if post.Visibility == store.PostVisibilityPrivate {
+			continue
+		}
+		// a slow mail provider shouldn't hold up the next batch
+		app.background(func() {
+			app.notifyFollowers(ctx, post)
+		})
+	}
+}
+
+func (app *application) notifyFollowers(ctx context.Context, post store.Post) {
+	followers, err := app.store.Followers.GetAllFollowers(ctx, post.UserId)
+	if err != nil {
+		app.logger.Errorw("error fetching followers", "post_id", post.ID, "error", err)
+		return
+	}
+	postURL := fmt.Sprintf("%s/posts/%d", app.config.frontendURL, post.ID)
+	for _, follower := range followers {
+		if ctx.Err() != nil {
+			return
+		}
+		vars := struct {
+			Username   string
+			AuthorName string
+			Title      string
+			PostURL    string
+		}{
+			Username:   follower.Username,
+			AuthorName: post.User.Username,
+			Title:      post.Title,
+			PostURL:    postURL,
+		}
+		if err := app.mailer.Send(mailer.PostPublishedTemplate, follower.Username, follower.Email, vars); err != nil {
+			app.logger.Errorw("error notifying follower", "post_id", post.ID, "follower_id", follower.ID, "error", err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"Blog/internal/store"
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+type reactionConfig struct {
+	allowed []string
+}
+
+type ReactPostPayload struct {
+	Reaction string `json:"reaction" validate:"required,max=32"`
+}
+
+var ErrReactionNotAllowed = errors.New("reaction is not allowed")
+
+// parseReactions reads the comma separated allow-list, ignoring spaces
+// around entries and empty entries.
+func parseReactions(list string) []string {
+	allowed := []string{}
+	for _, reaction := range strings.Split(list, ",") {
+		if reaction = strings.TrimSpace(reaction); reaction != "" {
+			allowed = append(allowed, reaction)
+		}
+	}
+	return allowed
+}
+
+func (app *application) reactPostHandler(res http.ResponseWriter, req *http.Request) {
+	payload, ok := app.readReaction(res, req)
+	if !ok {
+		return
+	}
+	if !slices.Contains(app.config.reactions.allowed, payload.Reaction) {
+		app.badRequestError(res, req, ErrReactionNotAllowed)
+		return
+	}
+	post := getPostFromCtx(req)
+	user := getAuthUser(req)
+	app.writeToggleResult(res, req, app.store.Reactions.Add(req.Context(), user.ID, post.ID, payload.Reaction))
+}
+
+// unreactPostHandler skips the allow-list so reactions that were allowed
+// when they were left can still be taken back.
+func (app *application) unreactPostHandler(res http.ResponseWriter, req *http.Request) {
+	payload, ok := app.readReaction(res, req)
+	if !ok {
+		return
+	}
+	post := getPostFromCtx(req)
+	user := getAuthUser(req)
+	app.writeToggleResult(res, req, app.store.Reactions.Remove(req.Context(), user.ID, post.ID, payload.Reaction))
+}
+
+// readReaction decodes and validates the payload, writing the error
+// response itself when it is not ok.
+func (app *application) readReaction(res http.ResponseWriter, req *http.Request) (*ReactPostPayload, bool) {
+	var payload ReactPostPayload
+	if err := readJSON(res, req, &payload); err != nil {
+		app.badRequestError(res, req, err)
+		return nil, false
+	}
+	if err := validate.Struct(payload); err != nil {
+		app.badRequestError(res, req, err)
+		return nil, false
+	}
+	return &payload, true
+}
+
+// attachReactions fills in the reaction summary of every post in the list.
+func (app *application) attachReactions(ctx context.Context, viewerID int64, posts []store.PostWithMetaData) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+	summaries, err := app.store.Reactions.GetSummaries(ctx, viewerID, ids)
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		posts[i].Reactions = summaries[posts[i].ID]
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"Blog/internal/diff"
+	"Blog/internal/store"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type RevisionDiff struct {
+	From    int         `json:"from"`
+	To      int         `json:"to"`
+	Title   []diff.Line `json:"title"`
+	Content []diff.Line `json:"content"`
+}
+
+func (app *application) getPostRevisionsHandler(res http.ResponseWriter, req *http.Request) {
+	post := getPostFromCtx(req)
+	revisions, err := app.store.Revisions.GetByPostID(req.Context(), post.ID)
+	if err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+	if err := app.jsonResponse(res, http.StatusOK, revisions); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+}
+
+func (app *application) getPostRevisionHandler(res http.ResponseWriter, req *http.Request) {
+	version, err := strconv.Atoi(chi.URLParam(req, "version"))
+	if err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	revision, err := app.getRevision(req, version)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(res, req, err)
+		default:
+			app.internalServerError(res, req, err)
+		}
+		return
+	}
+	if err := app.jsonResponse(res, http.StatusOK, revision); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+}
+
+func (app *application) diffPostRevisionsHandler(res http.ResponseWriter, req *http.Request) {
+	qs := req.URL.Query()
+	from, err := strconv.Atoi(qs.Get("from"))
+	if err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	to, err := strconv.Atoi(qs.Get("to"))
+	if err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	fromRevision, err := app.getRevision(req, from)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(res, req, err)
+		default:
+			app.internalServerError(res, req, err)
+		}
+		return
+	}
+	toRevision, err := app.getRevision(req, to)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(res, req, err)
+		default:
+			app.internalServerError(res, req, err)
+		}
+		return
+	}
+	result := RevisionDiff{
+		From:    from,
+		To:      to,
+		Title:   diff.Lines(fromRevision.Title, toRevision.Title),
+		Content: diff.Lines(fromRevision.Content, toRevision.Content),
+	}
+	if err := app.jsonResponse(res, http.StatusOK, result); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+}
+
+// restorePostRevisionHandler writes an older revision back as a new version.
+func (app *application) restorePostRevisionHandler(res http.ResponseWriter, req *http.Request) {
+	post := getPostFromCtx(req)
+	version, err := strconv.Atoi(chi.URLParam(req, "version"))
+	if err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	ctx := req.Context()
+	revision, err := app.store.Revisions.GetByVersion(ctx, post.ID, version)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(res, req, err)
+		default:
+			app.internalServerError(res, req, err)
+		}
+		return
+	}
+	post.Title = revision.Title
+	post.Content = revision.Content
+	if err := app.store.Posts.Update(ctx, post); err != nil {
+		switch {
+		case errors.Is(err, store.ErrVersionMismatch):
+			app.conflictError(res, req, err)
+		default:
+			app.internalServerError(res, req, err)
+		}
+		return
+	}
+	if err := app.jsonResponse(res, http.StatusOK, post); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+}
+
+// getRevision returns the stored revision for version, or the post itself
+// when version is the current one.
+func (app *application) getRevision(req *http.Request, version int) (*store.PostRevision, error) {
+	post := getPostFromCtx(req)
+	if version == post.Version {
+		return &store.PostRevision{
+			PostID:    post.ID,
+			Version:   post.Version,
+			Title:     post.Title,
+			Content:   post.Content,
+			CreatedAt: post.UpdatedAt,
+		}, nil
+	}
+	return app.store.Revisions.GetByVersion(req.Context(), post.ID, version)
+}
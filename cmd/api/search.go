@@ -0,0 +1,81 @@
+package main
+
+import (
+	"Blog/internal/store"
+	"Blog/internal/store/paginate"
+	"net/http"
+)
+
+// searchPostsHandler ranks published posts by how well they match q. Quoted
+// words are matched as a phrase and a trailing * matches by prefix.
+func (app *application) searchPostsHandler(res http.ResponseWriter, req *http.Request) {
+	sq := &paginate.SearchPaginateQuery{}
+	if err := sq.Parse(req); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	if err := validate.Struct(sq); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	results, err := app.store.Posts.Search(req.Context(), getViewerID(req), sq)
+	if err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+	if err := app.jsonResponse(res, http.StatusOK, results); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+}
+
+type SearchResults struct {
+	Users []store.User           `json:"users"`
+	Posts []store.PostSuggestion `json:"posts"`
+	Tags  []store.TagCount       `json:"tags"`
+}
+
+// searchHandler searches users, posts and tags at once, returning up to limit
+// results of each type. mode=prefix only matches the start of usernames,
+// titles and tags, which is what type-ahead boxes want.
+func (app *application) searchHandler(res http.ResponseWriter, req *http.Request) {
+	uq := &paginate.UnifiedSearchQuery{}
+	if err := uq.Parse(req); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	if err := validate.Struct(uq); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	ctx := req.Context()
+	viewer := getViewerID(req)
+	results := SearchResults{
+		Users: []store.User{},
+		Posts: []store.PostSuggestion{},
+		Tags:  []store.TagCount{},
+	}
+	var err error
+	if uq.Includes("users") {
+		if results.Users, err = app.store.Search.Users(ctx, viewer, uq); err != nil {
+			app.internalServerError(res, req, err)
+			return
+		}
+	}
+	if uq.Includes("posts") {
+		if results.Posts, err = app.store.Search.Posts(ctx, viewer, uq); err != nil {
+			app.internalServerError(res, req, err)
+			return
+		}
+	}
+	if uq.Includes("tags") {
+		if results.Tags, err = app.store.Search.Tags(ctx, uq); err != nil {
+			app.internalServerError(res, req, err)
+			return
+		}
+	}
+	if err := app.jsonResponse(res, http.StatusOK, results); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"Blog/internal/store"
+	"Blog/internal/store/paginate"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/chi/v5"
+)
+
+var ErrEmptyTag = errors.New("tag is empty once normalized")
+
+type MergeTagsPayload struct {
+	Sources []string `json:"sources" validate:"required,min=1,max=20,dive,required,max=100"`
+	Target  string   `json:"target" validate:"required,max=100"`
+}
+
+type RenameTagPayload struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+type TagMergeResult struct {
+	Tag          string `json:"tag"`
+	UpdatedPosts int64  `json:"updated_posts"`
+}
+
+// listTagsHandler lists tags on published posts by how often they are used.
+func (app *application) listTagsHandler(res http.ResponseWriter, req *http.Request) {
+	pq := &paginate.PaginatedQuery{}
+	pq.SetDefaults()
+	if err := pq.Parse(req); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	if err := validate.Struct(pq); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	tags, err := app.store.Tags.List(req.Context(), pq)
+	if err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+	if err := app.jsonResponse(res, http.StatusOK, tags); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+}
+
+// trendingTagsHandler ranks tags by use over the last window (7 days unless
+// ?window= says otherwise, e.g. window=24h).
+func (app *application) trendingTagsHandler(res http.ResponseWriter, req *http.Request) {
+	tq := &paginate.TrendingTagQuery{}
+	if err := tq.Parse(req); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	if err := validate.Struct(tq); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	trends, err := app.store.Tags.Trending(req.Context(), tq)
+	if err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+	if err := app.jsonResponse(res, http.StatusOK, trends); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+}
+
+func (app *application) getTagPostsHandler(res http.ResponseWriter, req *http.Request) {
+	tag, err := getTagParam(req)
+	if err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	app.writePublishedPosts(res, req, 0, tag)
+}
+
+func (app *application) followTagHandler(res http.ResponseWriter, req *http.Request) {
+	tag, err := getTagParam(req)
+	if err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	user := getAuthUser(req)
+	app.writeToggleResult(res, req, app.store.Followers.FollowTag(req.Context(), user.ID, tag))
+}
+
+func (app *application) unfollowTagHandler(res http.ResponseWriter, req *http.Request) {
+	tag, err := getTagParam(req)
+	if err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	user := getAuthUser(req)
+	app.writeToggleResult(res, req, app.store.Followers.UnfollowTag(req.Context(), user.ID, tag))
+}
+
+func (app *application) getFollowedTagsHandler(res http.ResponseWriter, req *http.Request) {
+	user := getAuthUser(req)
+	tags, err := app.store.Followers.GetFollowedTags(req.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+	if err := app.jsonResponse(res, http.StatusOK, tags); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+}
+
+func (app *application) mergeTagsHandler(res http.ResponseWriter, req *http.Request) {
+	var payload MergeTagsPayload
+	if err := readJSON(res, req, &payload); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	if err := validate.Struct(payload); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	app.writeTagMerge(res, req, payload.Sources, payload.Target)
+}
+
+// renameTagHandler renames the tag in the path on every post that uses it.
+func (app *application) renameTagHandler(res http.ResponseWriter, req *http.Request) {
+	tag, err := getTagParam(req)
+	if err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	var payload RenameTagPayload
+	if err := readJSON(res, req, &payload); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	if err := validate.Struct(payload); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	app.writeTagMerge(res, req, []string{tag}, payload.Name)
+}
+
+func (app *application) writeTagMerge(res http.ResponseWriter, req *http.Request, sources []string, target string) {
+	target = store.NormalizeTag(target)
+	if target == "" {
+		app.badRequestError(res, req, ErrEmptyTag)
+		return
+	}
+	updated, err := app.store.Tags.Merge(req.Context(), sources, target)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(res, req, err)
+		default:
+			app.internalServerError(res, req, err)
+		}
+		return
+	}
+	result := TagMergeResult{Tag: target, UpdatedPosts: updated}
+	if err := app.jsonResponse(res, http.StatusOK, result); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+}
+
+func getTagParam(req *http.Request) (string, error) {
+	tag, err := url.PathUnescape(chi.URLParam(req, "tag"))
+	if err != nil {
+		return "", err
+	}
+	tag = store.NormalizeTag(tag)
+	if tag == "" {
+		return "", ErrEmptyTag
+	}
+	return tag, nil
+}
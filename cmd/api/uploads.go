@@ -0,0 +1,286 @@
+package main
+
+import (
+	"Blog/internal/blob"
+	"Blog/internal/store"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+var ErrFileTooLarge = errors.New("file exceeds the upload limit")
+
+// multipartOverhead leaves room for the boundaries and part headers around
+// the file itself.
+const multipartOverhead = 64 << 10
+
+type upload struct {
+	data        []byte
+	contentType string
+	thumbnail   *blob.Thumbnail
+}
+
+// readUpload reads the "file" part of a multipart body. The type is sniffed
+// from the bytes, never taken from the client, a thumbnail is made and
+// metadata such as the GPS position of a photo is stripped before anything
+// is stored.
+func (app *application) readUpload(res http.ResponseWriter, req *http.Request, maxSize int64) (*upload, error) {
+	req.Body = http.MaxBytesReader(res, req.Body, maxSize+multipartOverhead)
+	if err := req.ParseMultipartForm(1 << 20); err != nil {
+		return nil, err
+	}
+	defer req.MultipartForm.RemoveAll()
+	file, _, err := req.FormFile("file")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, ErrFileTooLarge
+	}
+	contentType, err := blob.SniffImage(data)
+	if err != nil {
+		return nil, err
+	}
+	thumbnail, err := blob.MakeThumbnail(data, app.config.upload.thumbnailSize)
+	if err != nil {
+		return nil, err
+	}
+	data, err = blob.StripMetadata(data, contentType)
+	if err != nil {
+		return nil, err
+	}
+	return &upload{data: data, contentType: contentType, thumbnail: thumbnail}, nil
+}
+
+func (app *application) writeUploadError(res http.ResponseWriter, req *http.Request, err error) {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr), errors.Is(err, ErrFileTooLarge), errors.Is(err, blob.ErrImageTooLarge):
+		app.payloadTooLargeError(res, req, err)
+	case errors.Is(err, blob.ErrUnsupportedType):
+		app.unsupportedMediaTypeError(res, req, err)
+	default:
+		app.badRequestError(res, req, err)
+	}
+}
+
+// putUpload stores the file and its thumbnail under prefix and describes
+// them as media owned by userId. The caller fills in the URLs.
+func (app *application) putUpload(ctx context.Context, prefix string, userId int64, up *upload) (*store.Media, error) {
+	name := uuid.NewString()
+	m := &store.Media{
+		UserID:       userId,
+		Key:          prefix + name + blob.Extension(up.contentType),
+		ThumbnailKey: prefix + name + "_thumb" + blob.Extension(up.thumbnail.ContentType),
+		ContentType:  up.contentType,
+		Size:         int64(len(up.data)),
+		Width:        up.thumbnail.Width,
+		Height:       up.thumbnail.Height,
+	}
+	if err := app.blobs.Put(ctx, m.Key, bytes.NewReader(up.data), m.Size, m.ContentType); err != nil {
+		return nil, err
+	}
+	thumb := up.thumbnail.Data
+	if err := app.blobs.Put(ctx, m.ThumbnailKey, bytes.NewReader(thumb), int64(len(thumb)), up.thumbnail.ContentType); err != nil {
+		app.removeMedia(ctx, &store.Media{Key: m.Key})
+		return nil, err
+	}
+	return m, nil
+}
+
+// setPostImageURLs points m at the handlers that serve post images. Unlike
+// avatars, post images aren't public blobs: they are served through the API
+// so the post's visibility and blocks apply to them too, and the URLs are
+// built per response instead of being stored.
+func (app *application) setPostImageURLs(postId int64, m *store.Media) {
+	m.URL = fmt.Sprintf("%s/v1/posts/%d/images/%d", app.config.apiURL, postId, m.ID)
+	m.ThumbnailURL = m.URL + "/thumbnail"
+}
+
+// removeMedia deletes the files behind m. Failures only leave orphaned
+// files behind, so they are logged rather than returned.
+func (app *application) removeMedia(ctx context.Context, m *store.Media) {
+	for _, key := range []string{m.Key, m.ThumbnailKey} {
+		if key == "" {
+			continue
+		}
+		if err := app.blobs.Delete(ctx, key); err != nil && !errors.Is(err, blob.ErrNotFound) {
+			app.logger.Warnw("failed to delete blob", "key", key, "error", err.Error())
+		}
+	}
+}
+
+func (app *application) uploadAvatarHandler(res http.ResponseWriter, req *http.Request) {
+	up, err := app.readUpload(res, req, app.config.upload.maxAvatarSize)
+	if err != nil {
+		app.writeUploadError(res, req, err)
+		return
+	}
+	user := getAuthUser(req)
+	ctx := req.Context()
+	m, err := app.putUpload(ctx, fmt.Sprintf("avatars/%d/", user.ID), user.ID, up)
+	if err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+	// avatars are public, anyone can fetch them from the blob store
+	m.URL = app.blobs.URL(m.Key)
+	m.ThumbnailURL = app.blobs.URL(m.ThumbnailKey)
+	previous, err := app.store.Media.SetAvatar(ctx, m)
+	if err != nil {
+		app.removeMedia(ctx, m)
+		app.internalServerError(res, req, err)
+		return
+	}
+	if previous != nil {
+		app.removeMedia(ctx, previous)
+	}
+	if err := app.jsonResponse(res, http.StatusOK, m); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+}
+
+func (app *application) deleteAvatarHandler(res http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	previous, err := app.store.Media.DeleteAvatar(ctx, getAuthUser(req).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(res, req, err)
+		default:
+			app.internalServerError(res, req, err)
+		}
+		return
+	}
+	app.removeMedia(ctx, previous)
+	res.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) uploadPostImageHandler(res http.ResponseWriter, req *http.Request) {
+	up, err := app.readUpload(res, req, app.config.upload.maxImageSize)
+	if err != nil {
+		app.writeUploadError(res, req, err)
+		return
+	}
+	post := getPostFromCtx(req)
+	ctx := req.Context()
+	m, err := app.putUpload(ctx, fmt.Sprintf("posts/%d/", post.ID), getAuthUser(req).ID, up)
+	if err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+	if err := app.store.Media.AddPostImage(ctx, post.ID, m); err != nil {
+		app.removeMedia(ctx, m)
+		app.internalServerError(res, req, err)
+		return
+	}
+	app.setPostImageURLs(post.ID, m)
+	if err := app.jsonResponse(res, http.StatusCreated, m); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+}
+
+func (app *application) getPostImagesHandler(res http.ResponseWriter, req *http.Request) {
+	post := getPostFromCtx(req)
+	images, err := app.store.Media.GetPostImages(req.Context(), post.ID)
+	if err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+	for i := range images {
+		app.setPostImageURLs(post.ID, &images[i])
+	}
+	if err := app.jsonResponse(res, http.StatusOK, images); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+}
+
+func (app *application) getPostImageFileHandler(res http.ResponseWriter, req *http.Request) {
+	app.servePostImage(res, req, false)
+}
+
+func (app *application) getPostImageThumbnailHandler(res http.ResponseWriter, req *http.Request) {
+	app.servePostImage(res, req, true)
+}
+
+// servePostImage streams an image of the post in context, which
+// postsContextMiddleware only loads for viewers allowed to see it.
+func (app *application) servePostImage(res http.ResponseWriter, req *http.Request, thumbnail bool) {
+	imageId, err := strconv.ParseInt(chi.URLParam(req, "imageId"), 10, 64)
+	if err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	ctx := req.Context()
+	m, err := app.store.Media.GetPostImage(ctx, getPostFromCtx(req).ID, imageId)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(res, req, err)
+		default:
+			app.internalServerError(res, req, err)
+		}
+		return
+	}
+	key := m.Key
+	if thumbnail {
+		key = m.ThumbnailKey
+	}
+	body, err := app.blobs.Get(ctx, key)
+	if err != nil {
+		switch {
+		case errors.Is(err, blob.ErrNotFound):
+			app.notFoundError(res, req, err)
+		default:
+			app.internalServerError(res, req, err)
+		}
+		return
+	}
+	defer body.Close()
+	res.Header().Set("Content-Type", mime.TypeByExtension(path.Ext(key)))
+	res.Header().Set("X-Content-Type-Options", "nosniff")
+	// access depends on the viewer, so shared caches must not keep it
+	res.Header().Set("Cache-Control", "private, max-age=300")
+	res.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(res, body); err != nil {
+		app.logger.Warnw("failed to stream post image", "key", key, "error", err.Error())
+	}
+}
+
+func (app *application) deletePostImageHandler(res http.ResponseWriter, req *http.Request) {
+	imageId, err := strconv.ParseInt(chi.URLParam(req, "imageId"), 10, 64)
+	if err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	ctx := req.Context()
+	m, err := app.store.Media.DeletePostImage(ctx, getPostFromCtx(req).ID, imageId)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(res, req, err)
+		default:
+			app.internalServerError(res, req, err)
+		}
+		return
+	}
+	app.removeMedia(ctx, m)
+	res.WriteHeader(http.StatusNoContent)
+}
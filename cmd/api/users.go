@@ -2,6 +2,7 @@ package main
 
 import (
 	"Blog/internal/store"
+	"Blog/internal/store/paginate"
 	"context"
 	"errors"
 	"net/http"
@@ -14,9 +15,20 @@ type userKey string
 
 const ctxUser userKey = "user"
 
+type UserProfile struct {
+	*store.User
+	store.UserStats
+}
+
 func (app *application) getUserHandler(res http.ResponseWriter, req *http.Request) {
 	user := getUserFromContext(req)
-	if err := app.jsonResponse(res, http.StatusFound, user); err != nil {
+	stats, err := app.store.Users.GetStats(req.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+	profile := UserProfile{User: user, UserStats: *stats}
+	if err := app.jsonResponse(res, http.StatusFound, profile); err != nil {
 		app.internalServerError(res, req, err)
 		return
 	}
@@ -26,7 +38,21 @@ func (app *application) followUserHandler(res http.ResponseWriter, req *http.Req
 	followee := getAuthUser(req)
 	follower := getUserFromContext(req)
 	ctx := req.Context()
-	err := app.store.Followers.Follow(ctx, followee.ID, follower.ID)
+	blocked, err := app.store.Blocks.IsBlocked(ctx, followee.ID, follower.ID)
+	if err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+	if blocked {
+		app.forbiddenError(res, req, ErrBlocked)
+		return
+	}
+	// private accounts have to approve the follow first
+	if follower.IsPrivate && follower.ID != followee.ID {
+		err = app.store.Followers.RequestFollow(ctx, followee.ID, follower.ID)
+	} else {
+		err = app.store.Followers.Follow(ctx, followee.ID, follower.ID)
+	}
 	if err != nil {
 		switch err {
 		case store.ErrConflict:
@@ -36,6 +62,12 @@ func (app *application) followUserHandler(res http.ResponseWriter, req *http.Req
 		}
 		return
 	}
+	if follower.IsPrivate && follower.ID != followee.ID {
+		if err := app.jsonResponse(res, http.StatusAccepted, FollowStatus{Status: "requested"}); err != nil {
+			app.internalServerError(res, req, err)
+		}
+		return
+	}
 	if err := app.jsonResponse(res, http.StatusNoContent, struct{}{}); err != nil {
 		app.internalServerError(res, req, err)
 		return
@@ -57,6 +89,41 @@ func (app *application) unfollowUserHandler(res http.ResponseWriter, req *http.R
 
 }
 
+func (app *application) getUserFollowersHandler(res http.ResponseWriter, req *http.Request) {
+	app.writeFollowList(res, req, app.store.Followers.GetFollowers)
+}
+
+func (app *application) getUserFollowingHandler(res http.ResponseWriter, req *http.Request) {
+	app.writeFollowList(res, req, app.store.Followers.GetFollowing)
+}
+
+type followListFunc func(context.Context, int64, int64, *paginate.PaginatedQuery) ([]store.UserWithMetaData, error)
+
+// writeFollowList responds with a page of the user in the path's followers or
+// followees, flagging the ones the requester follows.
+func (app *application) writeFollowList(res http.ResponseWriter, req *http.Request, list followListFunc) {
+	pq := &paginate.PaginatedQuery{}
+	pq.SetDefaults()
+	if err := pq.Parse(req); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	if err := validate.Struct(pq); err != nil {
+		app.badRequestError(res, req, err)
+		return
+	}
+	user := getUserFromContext(req)
+	users, err := list(req.Context(), user.ID, getViewerID(req), pq)
+	if err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+	if err := app.jsonResponse(res, http.StatusOK, users); err != nil {
+		app.internalServerError(res, req, err)
+		return
+	}
+}
+
 func (app *application) usersContextMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
 		userId, err := strconv.ParseInt(chi.URLParam(req, "userId"), 10, 64)
@@ -85,6 +152,15 @@ func getAuthUser(req *http.Request) *store.User {
 	return user
 }
 
+// getViewerID returns the id of the signed in user, or 0 for anonymous
+// requests that went through OptionalAuthenTokenMiddleware.
+func getViewerID(req *http.Request) int64 {
+	if user := getAuthUser(req); user != nil {
+		return user.ID
+	}
+	return 0
+}
+
 func (app *application) userActivationHandler(res http.ResponseWriter, req *http.Request) {
 	token := chi.URLParam(req, "token")
 	ctx := req.Context()
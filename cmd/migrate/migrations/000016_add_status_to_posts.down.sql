@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_posts_published_at;
+
+DROP INDEX IF EXISTS idx_posts_status;
+
+ALTER TABLE posts
+DROP COLUMN published_at;
+
+ALTER TABLE posts
+DROP COLUMN status;
@@ -0,0 +1,13 @@
+ALTER TABLE posts
+ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'published'
+    CHECK (status IN ('draft', 'published', 'archived'));
+
+ALTER TABLE posts
+ADD COLUMN published_at timestamp(0) with time zone;
+
+UPDATE posts SET published_at = created_at WHERE status = 'published';
+
+CREATE INDEX IF NOT EXISTS idx_posts_status ON posts (status);
+
+CREATE INDEX IF NOT EXISTS idx_posts_published_at ON posts (published_at)
+    WHERE status = 'published';
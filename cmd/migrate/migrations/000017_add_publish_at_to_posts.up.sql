@@ -0,0 +1,5 @@
+ALTER TABLE posts
+ADD COLUMN publish_at timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS idx_posts_publish_at ON posts (publish_at)
+    WHERE status = 'draft' AND publish_at IS NOT NULL;
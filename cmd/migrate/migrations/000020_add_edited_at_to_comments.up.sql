@@ -0,0 +1,2 @@
+ALTER TABLE comments
+ADD COLUMN edited_at timestamp(0) with time zone;
@@ -0,0 +1,5 @@
+ALTER TABLE comments
+ADD COLUMN likes_count bigint NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_comments_post_id_created_at ON comments (post_id, created_at)
+    WHERE parent_id IS NULL;
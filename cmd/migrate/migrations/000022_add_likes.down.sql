@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS comment_likes;
+
+DROP TABLE IF EXISTS post_likes;
+
+ALTER TABLE posts
+DROP COLUMN likes_count;
@@ -0,0 +1,23 @@
+ALTER TABLE posts
+ADD COLUMN likes_count bigint NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS post_likes (
+    user_id bigint NOT NULL,
+    post_id bigint NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, post_id),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS comment_likes (
+    user_id bigint NOT NULL,
+    comment_id bigint NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, comment_id),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (comment_id) REFERENCES comments (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_likes_post_id ON post_likes (post_id);
+CREATE INDEX IF NOT EXISTS idx_comment_likes_comment_id ON comment_likes (comment_id);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS post_reactions (
+    user_id bigint NOT NULL,
+    post_id bigint NOT NULL,
+    reaction VARCHAR(32) NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, post_id, reaction),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_reactions_post_id ON post_reactions (post_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_posts_title_prefix;
+
+DROP INDEX IF EXISTS idx_users_username_trgm;
+
+DROP INDEX IF EXISTS idx_users_username_prefix;
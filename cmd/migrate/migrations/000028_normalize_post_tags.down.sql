@@ -0,0 +1 @@
+-- the original spelling of normalized tags is gone, nothing to undo
@@ -0,0 +1,15 @@
+-- same rules as store.NormalizeTags: lower case, words joined by dashes,
+-- no empty tags or duplicates. Words are split on store.tagSpaceClass, the
+-- whitespace strings.Fields splits on.
+UPDATE posts p SET tags = ARRAY(
+    SELECT n.tag FROM (
+        SELECT array_to_string(array_remove(regexp_split_to_array(lower(u.tag),
+            '[\t\n\v\f\r \u0085\u00a0\u1680\u2000-\u200a\u2028\u2029\u202f\u205f\u3000]+'), ''), '-') AS tag,
+        MIN(u.ord) AS first
+        FROM unnest(p.tags) WITH ORDINALITY AS u(tag, ord)
+        GROUP BY 1
+    ) n
+    WHERE n.tag <> ''
+    ORDER BY n.first
+)
+WHERE p.tags IS NOT NULL;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS tag_followers (
+    user_id bigint NOT NULL,
+    tag VARCHAR(100) NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, tag),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_tag_followers_tag ON tag_followers (tag);
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS reserved_usernames;
+
+ALTER TABLE users
+DROP COLUMN username_changed_at,
+DROP COLUMN location,
+DROP COLUMN website,
+DROP COLUMN bio,
+DROP COLUMN display_name;
@@ -0,0 +1,14 @@
+ALTER TABLE users
+ADD COLUMN display_name VARCHAR(50) NOT NULL DEFAULT '',
+ADD COLUMN bio VARCHAR(300) NOT NULL DEFAULT '',
+ADD COLUMN website VARCHAR(200) NOT NULL DEFAULT '',
+ADD COLUMN location VARCHAR(100) NOT NULL DEFAULT '',
+ADD COLUMN username_changed_at timestamp(0) with time zone;
+
+-- old usernames stay taken for a while after a rename so links keep working
+CREATE TABLE IF NOT EXISTS reserved_usernames (
+    username VARCHAR(255) PRIMARY KEY,
+    user_id bigint NOT NULL,
+    reserved_until timestamp(0) with time zone NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
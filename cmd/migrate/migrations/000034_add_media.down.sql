@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS post_images;
+
+ALTER TABLE users DROP COLUMN IF EXISTS avatar_id;
+
+DROP TABLE IF EXISTS media;
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS media (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    key text NOT NULL UNIQUE,
+    url text NOT NULL,
+    thumbnail_key text NOT NULL,
+    thumbnail_url text NOT NULL,
+    content_type VARCHAR(50) NOT NULL,
+    size bigint NOT NULL,
+    width int NOT NULL,
+    height int NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+ALTER TABLE users
+ADD COLUMN avatar_id bigint REFERENCES media (id) ON DELETE SET NULL;
+
+CREATE TABLE IF NOT EXISTS post_images (
+    post_id bigint NOT NULL,
+    media_id bigint NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (post_id, media_id),
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+    FOREIGN KEY (media_id) REFERENCES media (id) ON DELETE CASCADE
+);
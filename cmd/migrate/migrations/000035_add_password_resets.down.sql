@@ -0,0 +1,5 @@
+ALTER TABLE users DROP COLUMN IF EXISTS password_changed_at;
+
+DELETE FROM user_invitations WHERE purpose <> 'activation';
+
+ALTER TABLE user_invitations DROP COLUMN IF EXISTS purpose;
@@ -0,0 +1,7 @@
+-- invitation tokens double as password reset tokens, told apart by purpose
+ALTER TABLE user_invitations
+ADD COLUMN purpose VARCHAR(20) NOT NULL DEFAULT 'activation';
+
+-- bearer tokens issued before this are no longer accepted
+ALTER TABLE users
+ADD COLUMN password_changed_at timestamp with time zone;
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS posts_tag_counts ON posts;
+
+DROP FUNCTION IF EXISTS refresh_tag_counts();
+
+DROP TABLE IF EXISTS tag_counts;
@@ -0,0 +1,39 @@
+-- how many public published posts use each tag, kept up to date by a
+-- trigger so tag typeahead doesn't unnest every post
+CREATE TABLE IF NOT EXISTS tag_counts (
+    tag VARCHAR(100) PRIMARY KEY,
+    uses bigint NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_tag_counts_prefix ON tag_counts (tag text_pattern_ops);
+
+CREATE INDEX IF NOT EXISTS idx_tag_counts_trgm ON tag_counts USING gin (tag gin_trgm_ops);
+
+INSERT INTO tag_counts (tag, uses)
+SELECT t.tag, COUNT(*)
+FROM posts p, unnest(p.tags) AS t(tag)
+WHERE p.status = 'published' AND p.visibility = 'public'
+GROUP BY t.tag;
+
+CREATE OR REPLACE FUNCTION refresh_tag_counts() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        IF OLD.status = 'published' AND OLD.visibility = 'public' THEN
+            UPDATE tag_counts SET uses = uses - 1 WHERE tag = ANY (OLD.tags);
+        END IF;
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        IF NEW.status = 'published' AND NEW.visibility = 'public' THEN
+            INSERT INTO tag_counts (tag, uses)
+            SELECT DISTINCT t.tag, 1 FROM unnest(NEW.tags) AS t(tag)
+            ON CONFLICT (tag) DO UPDATE SET uses = tag_counts.uses + 1;
+        END IF;
+    END IF;
+    DELETE FROM tag_counts WHERE uses <= 0;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER posts_tag_counts
+AFTER INSERT OR DELETE OR UPDATE OF tags, status, visibility ON posts
+FOR EACH ROW EXECUTE FUNCTION refresh_tag_counts();
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS post_images_delete_media ON post_images;
+
+DROP FUNCTION IF EXISTS delete_post_image_media();
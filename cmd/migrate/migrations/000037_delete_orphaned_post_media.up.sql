@@ -0,0 +1,12 @@
+-- post images belong to one post, so when the post goes (and its
+-- post_images rows with it) the media row goes too
+CREATE OR REPLACE FUNCTION delete_post_image_media() RETURNS trigger AS $$
+BEGIN
+    DELETE FROM media WHERE id = OLD.media_id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER post_images_delete_media
+AFTER DELETE ON post_images
+FOR EACH ROW EXECUTE FUNCTION delete_post_image_media();
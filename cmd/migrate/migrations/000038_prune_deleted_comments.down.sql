@@ -0,0 +1 @@
+-- pruned placeholders can't be brought back
@@ -0,0 +1,12 @@
+-- deleted placeholders are only kept while they have replies, remove the
+-- ones left behind before that rule, leaves first
+DO $$
+BEGIN
+    LOOP
+        DELETE FROM comments c
+        WHERE c.deleted_at IS NOT NULL
+            AND NOT EXISTS (SELECT 1 FROM comments r WHERE r.parent_id = c.id);
+        EXIT WHEN NOT FOUND;
+    END LOOP;
+END;
+$$;
@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS comment_likes_count ON comment_likes;
+
+DROP FUNCTION IF EXISTS count_comment_likes();
+
+DROP TRIGGER IF EXISTS post_likes_count ON post_likes;
+
+DROP FUNCTION IF EXISTS count_post_likes();
@@ -0,0 +1,36 @@
+-- like counters follow the like rows, including rows removed by a cascade
+-- when a user or comment is deleted
+CREATE OR REPLACE FUNCTION count_post_likes() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        UPDATE posts SET likes_count = likes_count + 1 WHERE id = NEW.post_id;
+    ELSE
+        UPDATE posts SET likes_count = likes_count - 1 WHERE id = OLD.post_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER post_likes_count
+AFTER INSERT OR DELETE ON post_likes
+FOR EACH ROW EXECUTE FUNCTION count_post_likes();
+
+CREATE OR REPLACE FUNCTION count_comment_likes() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        UPDATE comments SET likes_count = likes_count + 1 WHERE id = NEW.comment_id;
+    ELSE
+        UPDATE comments SET likes_count = likes_count - 1 WHERE id = OLD.comment_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER comment_likes_count
+AFTER INSERT OR DELETE ON comment_likes
+FOR EACH ROW EXECUTE FUNCTION count_comment_likes();
+
+-- fix counts that already drifted
+UPDATE posts p SET likes_count = (SELECT COUNT(*) FROM post_likes pl WHERE pl.post_id = p.id);
+
+UPDATE comments c SET likes_count = (SELECT COUNT(*) FROM comment_likes cl WHERE cl.comment_id = c.id);
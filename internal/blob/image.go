@@ -0,0 +1,153 @@
+package blob
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported file type")
+	ErrImageTooLarge   = errors.New("image dimensions are too large")
+)
+
+// maxPixels keeps a small file that decodes into a huge image from
+// exhausting memory.
+const maxPixels = 40_000_000
+
+var imageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// SniffImage detects the content type from the data itself, ignoring
+// whatever the client claimed, and only accepts images we can thumbnail.
+func SniffImage(data []byte) (string, error) {
+	contentType := http.DetectContentType(data)
+	if !imageTypes[contentType] {
+		return "", ErrUnsupportedType
+	}
+	return contentType, nil
+}
+
+// Extension returns the file extension for an accepted content type.
+func Extension(contentType string) string {
+	switch contentType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	}
+	return ""
+}
+
+// StripMetadata re-encodes JPEG and PNG images so that only the pixels are
+// kept, dropping EXIF data such as the GPS position a photo was taken at.
+// GIFs carry no such metadata and are returned as they are.
+func StripMetadata(data []byte, contentType string) ([]byte, error) {
+	if contentType != "image/jpeg" && contentType != "image/png" {
+		return data, nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+	var buf bytes.Buffer
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90})
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type Thumbnail struct {
+	Data        []byte
+	ContentType string
+	// Width and Height are the size of the original image.
+	Width  int
+	Height int
+}
+
+// MakeThumbnail scales the image down so neither side is longer than size.
+// JPEGs stay JPEGs, everything else becomes a PNG to keep transparency.
+func MakeThumbnail(data []byte, size int) (*Thumbnail, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrImageTooLarge
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	thumb := scaleDown(src, size)
+	var buf bytes.Buffer
+	contentType := "image/png"
+	if format == "jpeg" {
+		contentType = "image/jpeg"
+		err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(&buf, thumb)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Thumbnail{Data: buf.Bytes(), ContentType: contentType, Width: cfg.Width, Height: cfg.Height}, nil
+}
+
+// scaleDown shrinks src with a box filter, averaging every source pixel
+// that falls into a target pixel. Images already small enough are copied.
+func scaleDown(src image.Image, size int) *image.NRGBA {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	tw, th := w, h
+	if w > size || h > size {
+		if w >= h {
+			tw, th = size, max(1, h*size/w)
+		} else {
+			tw, th = max(1, w*size/h), size
+		}
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := y*h/th, max((y+1)*h/th, y*h/th+1)
+		for x := 0; x < tw; x++ {
+			x0, x1 := x*w/tw, max((x+1)*w/tw, x*w/tw+1)
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := color.NRGBA64Model.Convert(src.At(bounds.Min.X+sx, bounds.Min.Y+sy)).(color.NRGBA64)
+					r += uint64(c.R)
+					g += uint64(c.G)
+					b += uint64(c.B)
+					a += uint64(c.A)
+					n++
+				}
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(b / n >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+	return dst
+}
+
+// keep gif registered with image.Decode
+var _ = gif.Decode
@@ -0,0 +1,23 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	got := Lines("a\nb\nc", "a\nc\nd")
+	want := []Line{
+		{Op: OpEqual, Text: "a"},
+		{Op: OpDelete, Text: "b"},
+		{Op: OpEqual, Text: "c"},
+		{Op: OpInsert, Text: "d"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v; got %+v", want, got)
+	}
+
+	if got := Lines("", ""); len(got) != 0 {
+		t.Errorf("expected empty diff; got %+v", got)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"time"
 )
 
 func GetString(key, fallback string) string {
@@ -38,3 +39,29 @@ func GetBool(key string, fallback bool) bool {
 	}
 	return boolVal
 }
+
+func GetFloat(key string, fallback float64) float64 {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	valAsFloat, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		log.Println(err)
+		return fallback
+	}
+	return valAsFloat
+}
+
+func GetDuration(key string, fallback time.Duration) time.Duration {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	valAsDuration, err := time.ParseDuration(val)
+	if err != nil {
+		log.Println(err)
+		return fallback
+	}
+	return valAsDuration
+}
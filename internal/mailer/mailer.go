@@ -8,6 +8,8 @@ const (
 	FromName               = "BloggerSpot"
 	MaxRetries             = 3
 	UserActivationTemplate = "user_invitation.tmpl"
+	PostPublishedTemplate  = "post_published.tmpl"
+	PasswordResetTemplate  = "password_reset.tmpl"
 )
 
 //go:embed "templates"
@@ -0,0 +1,41 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderEscapesBody(t *testing.T) {
+	vars := struct {
+		Username   string
+		AuthorName string
+		Title      string
+		PostURL    string
+	}{
+		Username:   "reader",
+		AuthorName: "author",
+		Title:      `<a href="https://evil.example">click</a>`,
+		PostURL:    "https://bloggerspot.xyz/posts/1",
+	}
+	subject, body, err := render(PostPublishedTemplate, vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject != "author published a new post" {
+		t.Errorf("subject = %q", subject)
+	}
+	if strings.Contains(body, `<a href="https://evil.example">`) {
+		t.Error("title markup was rendered into the body")
+	}
+	if !strings.Contains(body, "&lt;a href=") {
+		t.Error("title was not escaped in the body")
+	}
+}
+
+func TestTemplatesRender(t *testing.T) {
+	for _, name := range []string{UserActivationTemplate, PostPublishedTemplate, PasswordResetTemplate} {
+		if _, _, err := render(name, map[string]string{}); err != nil {
+			t.Errorf("render(%s): %v", name, err)
+		}
+	}
+}
@@ -3,7 +3,9 @@ package mailer
 import (
 	"bytes"
 	"fmt"
+	htmltemplate "html/template"
 	"log"
+	"strings"
 	"text/template"
 	"time"
 
@@ -27,23 +29,16 @@ func NewResend(apikey, fromEmail string) *ResendGridMailer {
 
 func (m *ResendGridMailer) Send(templateFile, username, email string, data any) error {
 
-	templ, err := template.ParseFS(FS, "templates/"+templateFile)
+	subject, body, err := render(templateFile, data)
 	if err != nil {
 		return err
 	}
 
-	body := new(bytes.Buffer)
-
-	err = templ.ExecuteTemplate(body, "body", data)
-
 	params := &resend.SendEmailRequest{
 		To:      []string{email},
 		From:    m.fromEmail,
-		Subject: "Activation Code",
-		Html:    body.String(),
-	}
-	if err != nil {
-		return err
+		Subject: subject,
+		Html:    body,
 	}
 
 	for i := 0; i < MaxRetries; i++ {
@@ -60,3 +55,25 @@ func (m *ResendGridMailer) Send(templateFile, username, email string, data any)
 
 	return fmt.Errorf("failed to sent email")
 }
+
+// render executes a template's subject as plain text and its body as HTML,
+// escaping whatever users wrote, like post titles.
+func render(templateFile string, data any) (string, string, error) {
+	subjectTempl, err := template.ParseFS(FS, "templates/"+templateFile)
+	if err != nil {
+		return "", "", err
+	}
+	bodyTempl, err := htmltemplate.ParseFS(FS, "templates/"+templateFile)
+	if err != nil {
+		return "", "", err
+	}
+	subject := new(bytes.Buffer)
+	if err := subjectTempl.ExecuteTemplate(subject, "subject", data); err != nil {
+		return "", "", err
+	}
+	body := new(bytes.Buffer)
+	if err := bodyTempl.ExecuteTemplate(body, "body", data); err != nil {
+		return "", "", err
+	}
+	return strings.TrimSpace(subject.String()), body.String(), nil
+}
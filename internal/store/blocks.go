@@ -0,0 +1,148 @@
+package store
+
+import (
+	"Blog/internal/store/paginate"
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// BlockStore keeps who blocked and who muted whom. A block hides both users
+// from each other everywhere, a mute only keeps the muted user out of the
+// muter's feed.
+type BlockStore struct {
+	db *sql.DB
+}
+
+// notBlocked is a condition that holds unless either user has blocked the
+// other. Both arguments are SQL expressions.
+func notBlocked(viewer string, author string) string {
+	return `NOT EXISTS (SELECT 1 FROM user_blocks ub WHERE
+		(ub.blocker_id = ` + viewer + ` AND ub.blocked_id = ` + author + `)
+		OR (ub.blocker_id = ` + author + ` AND ub.blocked_id = ` + viewer + `))`
+}
+
+// notMuted holds unless viewer has muted author.
+func notMuted(viewer string, author string) string {
+	return `NOT EXISTS (SELECT 1 FROM user_mutes um WHERE um.muter_id = ` + viewer + ` AND um.muted_id = ` + author + `)`
+}
+
+// Block also drops any follow or follow request between the two users, in
+// both directions, along with the posts it put on their timelines.
+func (s *BlockStore) Block(ctx context.Context, blockerId int64, blockedId int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+		query := `INSERT INTO user_blocks (blocker_id, blocked_id) VALUES ( $1 , $2 )`
+		if _, err := tx.ExecContext(ctx, query, blockerId, blockedId); err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				return ErrConflict
+			}
+			return err
+		}
+		query = `DELETE FROM followers WHERE (user_id = $1 AND follower_id = $2) OR (user_id = $2 AND follower_id = $1)`
+		if _, err := tx.ExecContext(ctx, query, blockerId, blockedId); err != nil {
+			return err
+		}
+		query = `DELETE FROM follow_requests WHERE (requester_id = $1 AND target_id = $2) OR (requester_id = $2 AND target_id = $1)`
+		if _, err := tx.ExecContext(ctx, query, blockerId, blockedId); err != nil {
+			return err
+		}
+		timelines := &TimelineStore{s.db}
+		if err := timelines.prune(ctx, tx, blockerId, blockedId); err != nil {
+			return err
+		}
+		return timelines.prune(ctx, tx, blockedId, blockerId)
+	})
+}
+
+// Unblock lifts the block. Follows removed by it are not restored.
+func (s *BlockStore) Unblock(ctx context.Context, blockerId int64, blockedId int64) error {
+	return s.remove(ctx, `DELETE FROM user_blocks WHERE blocker_id = $1 AND blocked_id = $2`, blockerId, blockedId)
+}
+
+func (s *BlockStore) Mute(ctx context.Context, muterId int64, mutedId int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `INSERT INTO user_mutes (muter_id, muted_id) VALUES ( $1 , $2 )`
+	if _, err := s.db.ExecContext(ctx, query, muterId, mutedId); err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return ErrConflict
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *BlockStore) Unmute(ctx context.Context, muterId int64, mutedId int64) error {
+	return s.remove(ctx, `DELETE FROM user_mutes WHERE muter_id = $1 AND muted_id = $2`, muterId, mutedId)
+}
+
+func (s *BlockStore) remove(ctx context.Context, query string, userId int64, otherId int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	res, err := s.db.ExecContext(ctx, query, userId, otherId)
+	if err != nil {
+		return err
+	}
+	rows_affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows_affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// IsBlocked reports whether either user has blocked the other.
+func (s *BlockStore) IsBlocked(ctx context.Context, userId int64, otherId int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `SELECT NOT ` + notBlocked("$1::bigint", "$2::bigint")
+	var blocked bool
+	if err := s.db.QueryRowContext(ctx, query, userId, otherId).Scan(&blocked); err != nil {
+		return false, err
+	}
+	return blocked, nil
+}
+
+// GetBlocked returns one page of the users blockerId has blocked, newest first.
+func (s *BlockStore) GetBlocked(ctx context.Context, blockerId int64, pageQuery *paginate.PaginatedQuery) ([]User, error) {
+	query := `SELECT u.id , u.username , u.created_at FROM user_blocks b
+	JOIN users u ON u.id = b.blocked_id
+	WHERE b.blocker_id = $1
+	ORDER BY b.created_at DESC, u.id DESC
+	LIMIT $2 OFFSET $3`
+	return s.list(ctx, query, blockerId, pageQuery)
+}
+
+// GetMuted returns one page of the users muterId has muted, newest first.
+func (s *BlockStore) GetMuted(ctx context.Context, muterId int64, pageQuery *paginate.PaginatedQuery) ([]User, error) {
+	query := `SELECT u.id , u.username , u.created_at FROM user_mutes m
+	JOIN users u ON u.id = m.muted_id
+	WHERE m.muter_id = $1
+	ORDER BY m.created_at DESC, u.id DESC
+	LIMIT $2 OFFSET $3`
+	return s.list(ctx, query, muterId, pageQuery)
+}
+
+func (s *BlockStore) list(ctx context.Context, query string, userId int64, pageQuery *paginate.PaginatedQuery) ([]User, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	rows, err := s.db.QueryContext(ctx, query, userId, pageQuery.Limit, pageQuery.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	users := []User{}
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.ID, &u.Username, &u.CreatedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, nil
+}
@@ -0,0 +1,205 @@
+package store
+
+import (
+	"Blog/internal/store/paginate"
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+type ReadingList struct {
+	ID        int64  `json:"id"`
+	UserID    int64  `json:"user_id"`
+	Name      string `json:"name"`
+	CreatedAt string `json:"created_at"`
+	PostCount int64  `json:"post_count"`
+}
+
+type BookmarkStore struct {
+	db *sql.DB
+}
+
+func (s *BookmarkStore) CreateList(ctx context.Context, list *ReadingList) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `INSERT INTO reading_lists (user_id,name) VALUES ( $1 , $2 ) RETURNING id , created_at`
+	err := s.db.QueryRowContext(ctx, query, list.UserID, list.Name).Scan(&list.ID, &list.CreatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return ErrConflict
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *BookmarkStore) GetLists(ctx context.Context, userId int64) ([]ReadingList, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `SELECT rl.id , rl.user_id , rl.name , rl.created_at , COUNT(b.post_id)
+	FROM reading_lists rl
+	LEFT JOIN bookmarks b ON b.list_id = rl.id
+	WHERE rl.user_id = $1
+	GROUP BY rl.id
+	ORDER BY rl.created_at ASC`
+	rows, err := s.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	lists := []ReadingList{}
+	for rows.Next() {
+		var l ReadingList
+		if err := rows.Scan(&l.ID, &l.UserID, &l.Name, &l.CreatedAt, &l.PostCount); err != nil {
+			return nil, err
+		}
+		lists = append(lists, l)
+	}
+	return lists, nil
+}
+
+func (s *BookmarkStore) GetListByID(ctx context.Context, listId int64) (*ReadingList, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `SELECT rl.id , rl.user_id , rl.name , rl.created_at ,
+	(SELECT COUNT(*) FROM bookmarks b WHERE b.list_id = rl.id)
+	FROM reading_lists rl WHERE rl.id = $1`
+	var l ReadingList
+	err := s.db.QueryRowContext(ctx, query, listId).Scan(&l.ID, &l.UserID, &l.Name, &l.CreatedAt, &l.PostCount)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &l, nil
+}
+
+func (s *BookmarkStore) DeleteList(ctx context.Context, listId int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `DELETE FROM reading_lists WHERE id = $1`
+	res, err := s.db.ExecContext(ctx, query, listId)
+	if err != nil {
+		return err
+	}
+	rows_affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows_affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *BookmarkStore) AddPost(ctx context.Context, listId int64, postId int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `INSERT INTO bookmarks (list_id,post_id) VALUES ( $1 , $2 )`
+	_, err := s.db.ExecContext(ctx, query, listId, postId)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return ErrConflict
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *BookmarkStore) RemovePost(ctx context.Context, listId int64, postId int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `DELETE FROM bookmarks WHERE list_id = $1 AND post_id = $2`
+	res, err := s.db.ExecContext(ctx, query, listId, postId)
+	if err != nil {
+		return err
+	}
+	rows_affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows_affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetPosts lists the bookmarked posts of a reading list with the same search
+// and tag filters as the feed. Posts that are no longer published are left
+// out, unless the viewer wrote them. Pages are keyed on the time a post was
+// bookmarked.
+func (s *BookmarkStore) GetPosts(ctx context.Context, listId int64, viewerId int64, pageQuery *paginate.PostPaginateQuery) ([]PostWithMetaData, paginate.CursorPage, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	cursorAt, cursorID := cursorArgs(pageQuery)
+	_, op, order := pageQuery.Seek()
+	query := `SELECT
+	p.id,
+	p.user_id,
+	p.title,
+	p.content,
+	p.created_at,
+	p.updated_at,
+	p.version,
+	p.tags,
+	p.status,
+	p.visibility,
+	p.published_at,
+	p.likes_count,
+	EXISTS (SELECT 1 FROM post_likes pl WHERE pl.post_id = p.id AND pl.user_id = $2) AS liked,
+	u.username,
+	` + liveCommentCount + ` AS comment_count,
+	b.created_at
+FROM
+	bookmarks b
+	JOIN posts p ON p.id = b.post_id
+	LEFT JOIN users u ON p.user_id = u.id
+WHERE
+	b.list_id = $1
+	AND ( p.status = 'published' OR p.user_id = $2 )
+	AND ` + postVisibleTo("$2::bigint") + `
+	AND
+	(
+	p.title ILIKE  '%' || $3 || '%'
+	OR
+	p.content ILIKE  '%' || $3 || '%'
+	)
+	AND
+	( p.tags @> $4 OR $4 = '{}' )
+	AND
+	( $6::timestamptz IS NULL OR (b.created_at, b.post_id) ` + op + ` ($6::timestamptz, $7::bigint) )
+ORDER BY
+	b.created_at ` + order + `, b.post_id ` + order + ` LIMIT $5;`
+
+	// one extra row tells us whether there is another page
+	rows, err := s.db.QueryContext(ctx, query, listId, viewerId,
+		pageQuery.Search, pq.Array(NormalizeTags(pageQuery.Tags)),
+		pageQuery.Limit+1,
+		cursorAt, cursorID)
+	if err != nil {
+		return nil, paginate.CursorPage{}, err
+	}
+	defer rows.Close()
+	posts := []PostWithMetaData{}
+	for rows.Next() {
+		var p PostWithMetaData
+		err := rows.Scan(&p.ID, &p.UserId, &p.Title,
+			&p.Content, &p.CreatedAt, &p.UpdatedAt, &p.Version,
+			pq.Array(&p.Tags), &p.Status, &p.Visibility, &p.PublishedAt,
+			&p.Likes, &p.Liked,
+			&p.User.Username, &p.CommentCount, &p.BookmarkedAt)
+		if err != nil {
+			return nil, paginate.CursorPage{}, err
+		}
+		posts = append(posts, p)
+	}
+	posts, page := cursorPageBy(posts, pageQuery, func(p PostWithMetaData) paginate.Cursor {
+		return paginate.Cursor{CreatedAt: *p.BookmarkedAt, ID: p.ID}
+	})
+	return posts, page, nil
+}
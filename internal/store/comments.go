@@ -1,37 +1,68 @@
 package store
 
 import (
+	"Blog/internal/store/paginate"
 	"context"
 	"database/sql"
+	"errors"
 )
 
+const DeletedCommentPlaceholder = "[deleted]"
+
+// liveCommentCount counts the comments of post p, leaving out deleted
+// placeholders, the same way CountByPostID does.
+const liveCommentCount = `(SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id AND c.deleted_at IS NULL)`
+
 type CommentStore struct {
 	db *sql.DB
 }
 
 type Comment struct {
-	ID         int64  `json:"id"`
-	PostID     int64  `json:"post_id"`
-	UserID     int64  `json:"user_id"`
-	Content    string `json:"content"`
-	Created_At string `json:"created_at"`
-	Updated_At string `json:"updated_at"`
-	User       User   `json:"user"`
-	Likes      int64  `json:"likes"`
+	ID         int64     `json:"id"`
+	PostID     int64     `json:"post_id"`
+	UserID     int64     `json:"user_id"`
+	ParentID   *int64    `json:"parent_id,omitempty"`
+	Depth      int       `json:"depth"`
+	Content    string    `json:"content"`
+	Created_At string    `json:"created_at"`
+	Updated_At string    `json:"updated_at"`
+	EditedAt   *string   `json:"edited_at,omitempty"`
+	Deleted    bool      `json:"deleted"`
+	User       User      `json:"user"`
+	Likes      int64     `json:"likes"`
+	Liked      bool      `json:"liked"`
+	ReplyCount int64     `json:"reply_count"`
+	Replies    []Comment `json:"replies,omitempty"`
+}
+
+// redact replaces a deleted comment with a placeholder so its thread stays
+// intact without exposing what was said or by whom.
+func (c *Comment) redact() {
+	if !c.Deleted {
+		return
+	}
+	c.Content = DeletedCommentPlaceholder
+	c.EditedAt = nil
+	c.UserID = 0
+	c.User = User{}
 }
 
-func (c *CommentStore) GetByPostID(ctx context.Context, postID int64) ([]Comment, error) {
+// GetByPostID returns every comment on the post, leaving out those by users
+// viewerID has blocked or been blocked by.
+func (c *CommentStore) GetByPostID(ctx context.Context, postID int64, viewerID int64) ([]Comment, error) {
 	query := `SELECT
-	c.id , c.post_id, c.user_id , c.content,
-	 c.created_at,c.updated_at , u.username , u.id 
+	c.id , c.post_id, c.user_id , c.parent_id , c.depth , c.content,
+	 c.created_at,c.updated_at , c.edited_at , c.deleted_at IS NOT NULL , u.username , u.id ,
+	 c.likes_count
 FROM
 	COMMENTS as  c
 	JOIN USERS as u ON u.id = c.user_id
 WHERE
-	c.post_id = $1 
+	c.post_id = $1
+	AND ` + notBlocked("$2::bigint", "c.user_id") + `
 ORDER BY
 	c.created_at DESC;`
-	rows, err := c.db.QueryContext(ctx, query, postID)
+	rows, err := c.db.QueryContext(ctx, query, postID, viewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -40,23 +71,269 @@ ORDER BY
 	for rows.Next() {
 		var comment_row Comment
 		comment_row.User = User{}
-		err := rows.Scan(&comment_row.ID, &comment_row.PostID, &comment_row.UserID, &comment_row.Content,
-			&comment_row.Created_At, &comment_row.Updated_At, &comment_row.User.Username, &comment_row.User.ID)
+		err := rows.Scan(&comment_row.ID, &comment_row.PostID, &comment_row.UserID, &comment_row.ParentID,
+			&comment_row.Depth, &comment_row.Content,
+			&comment_row.Created_At, &comment_row.Updated_At, &comment_row.EditedAt, &comment_row.Deleted,
+			&comment_row.User.Username, &comment_row.User.ID, &comment_row.Likes)
 		if err != nil {
 			return nil, err
 		}
+		comment_row.redact()
 		comments = append(comments, comment_row)
 	}
 	return comments, nil
 }
 
+var commentSortOrders = map[string]string{
+	"newest":     "c.created_at DESC , c.id DESC",
+	"oldest":     "c.created_at ASC , c.id ASC",
+	"most_liked": "c.likes_count DESC , c.created_at DESC , c.id DESC",
+}
+
+// ListByPostID returns one page of a post's top level comments.
+func (c *CommentStore) ListByPostID(ctx context.Context, postID int64, viewerID int64, pageQuery *paginate.CommentPaginateQuery) ([]Comment, error) {
+	query := `SELECT
+	c.id , c.post_id, c.user_id , c.parent_id , c.depth , c.content,
+	 c.created_at,c.updated_at , c.edited_at , c.deleted_at IS NOT NULL , u.username , u.id ,
+	 c.likes_count ,
+	 EXISTS (SELECT 1 FROM comment_likes cl WHERE cl.comment_id = c.id AND cl.user_id = $4) AS liked ,
+	 (SELECT COUNT(*) FROM comments r WHERE r.parent_id = c.id) AS reply_count
+FROM
+	COMMENTS as  c
+	JOIN USERS as u ON u.id = c.user_id
+WHERE
+	c.post_id = $1
+	AND c.parent_id IS NULL
+	AND ` + notBlocked("$4::bigint", "c.user_id") + `
+ORDER BY
+	` + commentSortOrders[pageQuery.Sort] + `
+LIMIT $2 OFFSET $3;`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	rows, err := c.db.QueryContext(ctx, query, postID, pageQuery.Limit, pageQuery.Offset, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	comments := []Comment{}
+	for rows.Next() {
+		var comment Comment
+		err := rows.Scan(&comment.ID, &comment.PostID, &comment.UserID, &comment.ParentID,
+			&comment.Depth, &comment.Content,
+			&comment.Created_At, &comment.Updated_At, &comment.EditedAt, &comment.Deleted,
+			&comment.User.Username, &comment.User.ID, &comment.Likes, &comment.Liked, &comment.ReplyCount)
+		if err != nil {
+			return nil, err
+		}
+		comment.redact()
+		comments = append(comments, comment)
+	}
+	return comments, nil
+}
+
+func (c *CommentStore) CountByPostID(ctx context.Context, postID int64) (int64, error) {
+	query := `SELECT COUNT(*) FROM comments WHERE post_id = $1 AND deleted_at IS NULL`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	var count int64
+	if err := c.db.QueryRowContext(ctx, query, postID).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetByID loads a comment unless its author and viewerID have blocked one
+// another, in which case it reports ErrNotFound.
+func (c *CommentStore) GetByID(ctx context.Context, commentID int64, viewerID int64) (*Comment, error) {
+	query := `SELECT
+	c.id , c.post_id, c.user_id , c.parent_id , c.depth , c.content,
+	 c.created_at,c.updated_at , c.edited_at , c.deleted_at IS NOT NULL , u.username , u.id ,
+	 c.likes_count
+FROM
+	COMMENTS as  c
+	JOIN USERS as u ON u.id = c.user_id
+WHERE
+	c.id = $1
+	AND ` + notBlocked("$2::bigint", "c.user_id")
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	var comment Comment
+	err := c.db.QueryRowContext(ctx, query, commentID, viewerID).Scan(&comment.ID, &comment.PostID, &comment.UserID,
+		&comment.ParentID, &comment.Depth, &comment.Content,
+		&comment.Created_At, &comment.Updated_At, &comment.EditedAt, &comment.Deleted,
+		&comment.User.Username, &comment.User.ID, &comment.Likes)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &comment, nil
+}
+
+func (c *CommentStore) GetReplies(ctx context.Context, commentID int64, viewerID int64, pageQuery *paginate.PaginatedQuery) ([]Comment, error) {
+	query := `SELECT
+	c.id , c.post_id, c.user_id , c.parent_id , c.depth , c.content,
+	 c.created_at,c.updated_at , c.edited_at , c.deleted_at IS NOT NULL , u.username , u.id ,
+	 c.likes_count ,
+	 EXISTS (SELECT 1 FROM comment_likes cl WHERE cl.comment_id = c.id AND cl.user_id = $4) AS liked ,
+	 (SELECT COUNT(*) FROM comments r WHERE r.parent_id = c.id) AS reply_count
+FROM
+	COMMENTS as  c
+	JOIN USERS as u ON u.id = c.user_id
+WHERE
+	c.parent_id = $1
+	AND ` + notBlocked("$4::bigint", "c.user_id") + `
+ORDER BY
+	c.created_at ASC
+LIMIT $2 OFFSET $3;`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	rows, err := c.db.QueryContext(ctx, query, commentID, pageQuery.Limit, pageQuery.Offset, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	replies := []Comment{}
+	for rows.Next() {
+		var reply Comment
+		err := rows.Scan(&reply.ID, &reply.PostID, &reply.UserID, &reply.ParentID,
+			&reply.Depth, &reply.Content,
+			&reply.Created_At, &reply.Updated_At, &reply.EditedAt, &reply.Deleted,
+			&reply.User.Username, &reply.User.ID, &reply.Likes, &reply.Liked, &reply.ReplyCount)
+		if err != nil {
+			return nil, err
+		}
+		reply.redact()
+		replies = append(replies, reply)
+	}
+	return replies, nil
+}
+
+// Create adds the comment if its author may open the post, see
+// postVisibleTo. Returns ErrNotFound otherwise.
 func (c *CommentStore) Create(ctx context.Context, comment *Comment) error {
-	query := `INSERT INTO COMMENTS (post_id,user_id,content) VALUES( $1 , $2 , $3 ) RETURNING id`
+	query := `INSERT INTO COMMENTS (post_id,user_id,content,parent_id,depth)
+	SELECT p.id , $2::bigint , $3::text , $4::bigint , $5::int FROM posts p
+	WHERE p.id = $1 AND ( p.status = 'published' OR p.user_id = $2 )
+	AND ` + postVisibleTo("$2::bigint") + `
+	RETURNING id , created_at , updated_at`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	err := c.db.QueryRowContext(ctx, query, comment.PostID, comment.UserID, comment.Content,
+		comment.ParentID, comment.Depth).Scan(&comment.ID, &comment.Created_At, &comment.Updated_At)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *CommentStore) Update(ctx context.Context, comment *Comment) error {
+	query := `UPDATE comments SET content = $1 , updated_at = NOW() , edited_at = NOW()
+	WHERE id = $2 AND deleted_at IS NULL RETURNING updated_at , edited_at`
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
-	err := c.db.QueryRowContext(ctx, query, comment.PostID, comment.UserID, comment.Content).Scan(&comment.ID)
+	err := c.db.QueryRowContext(ctx, query, comment.Content, comment.ID).Scan(&comment.Updated_At, &comment.EditedAt)
 	if err != nil {
-		return err
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFound
+		default:
+			return err
+		}
 	}
 	return nil
 }
+
+// Delete removes a comment. A comment that still has replies is only marked
+// as deleted so the replies keep their place in the thread. Once a marked
+// comment loses its last reply it is removed too, up the chain.
+func (c *CommentStore) Delete(ctx context.Context, commentID int64) error {
+	return withTx(c.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+		var hasReplies bool
+		query := `SELECT EXISTS (SELECT 1 FROM comments WHERE parent_id = $1)`
+		if err := tx.QueryRowContext(ctx, query, commentID).Scan(&hasReplies); err != nil {
+			return err
+		}
+		if hasReplies {
+			query = `UPDATE comments SET deleted_at = NOW() , content = '' WHERE id = $1 AND deleted_at IS NULL`
+			res, err := tx.ExecContext(ctx, query, commentID)
+			if err != nil {
+				return err
+			}
+			rows_affected, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if rows_affected == 0 {
+				return ErrNotFound
+			}
+			return nil
+		}
+		var parentID *int64
+		query = `DELETE FROM comments WHERE id = $1 RETURNING parent_id`
+		if err := tx.QueryRowContext(ctx, query, commentID).Scan(&parentID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNotFound
+			}
+			return err
+		}
+		return pruneDeletedAncestors(ctx, tx, parentID)
+	})
+}
+
+// pruneDeletedAncestors removes deleted placeholders that no longer have
+// replies, starting at parentID and walking up the thread.
+func pruneDeletedAncestors(ctx context.Context, tx *sql.Tx, parentID *int64) error {
+	query := `DELETE FROM comments c
+	WHERE c.id = $1 AND c.deleted_at IS NOT NULL
+		AND NOT EXISTS (SELECT 1 FROM comments r WHERE r.parent_id = c.id)
+	RETURNING c.parent_id`
+	for parentID != nil {
+		var next *int64
+		err := tx.QueryRowContext(ctx, query, *parentID).Scan(&next)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		parentID = next
+	}
+	return nil
+}
+
+// BuildCommentTree nests comments under their parents, keeping the order
+// they were given in.
+func BuildCommentTree(comments []Comment) []Comment {
+	children := map[int64][]Comment{}
+	roots := []Comment{}
+	for _, comment := range comments {
+		if comment.ParentID == nil {
+			roots = append(roots, comment)
+			continue
+		}
+		children[*comment.ParentID] = append(children[*comment.ParentID], comment)
+	}
+	var attach func(comment *Comment)
+	attach = func(comment *Comment) {
+		comment.Replies = children[comment.ID]
+		comment.ReplyCount = int64(len(comment.Replies))
+		for i := range comment.Replies {
+			attach(&comment.Replies[i])
+		}
+	}
+	for i := range roots {
+		attach(&roots[i])
+	}
+	return roots
+}
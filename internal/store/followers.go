@@ -1,9 +1,10 @@
 package store
 
 import (
+	"Blog/internal/store/paginate"
 	"context"
 	"database/sql"
-	"fmt"
+	"time"
 
 	"github.com/lib/pq"
 )
@@ -18,25 +19,304 @@ type FollowerStore struct {
 	db *sql.DB
 }
 
+// Follow also backfills the followed user's posts into the follower's timeline.
 func (s *FollowerStore) Follow(ctx context.Context, followeeId int64, followerId int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		return s.follow(ctx, tx, followeeId, followerId)
+	})
+}
+
+// follow makes readerId follow authorId.
+func (s *FollowerStore) follow(ctx context.Context, tx *sql.Tx, readerId int64, authorId int64) error {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
-	fmt.Println(followeeId, followerId)
 	query := `INSERT INTO followers(user_id,follower_id) VALUES( $1 , $2 )`
-	_, err := s.db.ExecContext(ctx, query, followeeId, followerId)
+	_, err := tx.ExecContext(ctx, query, readerId, authorId)
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
 			return ErrConflict
 		}
 		return err
 	}
-	return err
+	timelines := &TimelineStore{s.db}
+	return timelines.backfill(ctx, tx, readerId, authorId)
 }
 
+// Unfollow also removes the unfollowed user's posts from the timeline and
+// withdraws a pending follow request.
 func (s *FollowerStore) Unfollow(ctx context.Context, followeeId int64, followerId int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+		query := `DELETE FROM followers WHERE user_id = $1 AND follower_id = $2`
+		if _, err := tx.ExecContext(ctx, query, followeeId, followerId); err != nil {
+			return err
+		}
+		query = `DELETE FROM follow_requests WHERE requester_id = $1 AND target_id = $2`
+		if _, err := tx.ExecContext(ctx, query, followeeId, followerId); err != nil {
+			return err
+		}
+		timelines := &TimelineStore{s.db}
+		return timelines.prune(ctx, tx, followeeId, followerId)
+	})
+}
+
+// GetAllFollowers returns everyone following userId, including their email.
+func (s *FollowerStore) GetAllFollowers(ctx context.Context, userId int64) ([]User, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `SELECT u.id , u.username , u.email FROM followers f
+	JOIN users u ON u.id = f.user_id
+	WHERE f.follower_id = $1 AND u.is_active = true`
+	rows, err := s.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	users := []User{}
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.ID, &u.Username, &u.Email); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, nil
+}
+
+// FollowTag adds tag to the tags whose posts show up in userId's feed.
+func (s *FollowerStore) FollowTag(ctx context.Context, userId int64, tag string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `INSERT INTO tag_followers(user_id,tag) VALUES( $1 , $2 )`
+	_, err := s.db.ExecContext(ctx, query, userId, NormalizeTag(tag))
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return ErrConflict
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *FollowerStore) UnfollowTag(ctx context.Context, userId int64, tag string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `DELETE FROM tag_followers WHERE user_id = $1 AND tag = $2`
+	res, err := s.db.ExecContext(ctx, query, userId, NormalizeTag(tag))
+	if err != nil {
+		return err
+	}
+	rows_affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows_affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *FollowerStore) GetFollowedTags(ctx context.Context, userId int64) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `SELECT tag FROM tag_followers WHERE user_id = $1 ORDER BY tag ASC`
+	rows, err := s.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// GetFollowers returns one page of the people following userId, newest
+// first. Follower is set on entries that viewerId follows.
+func (s *FollowerStore) GetFollowers(ctx context.Context, userId int64, viewerId int64, pageQuery *paginate.PaginatedQuery) ([]UserWithMetaData, error) {
+	query := `SELECT
+	u.id,
+	u.username,
+	u.created_at,
+	EXISTS (SELECT 1 FROM followers v WHERE v.user_id = $2 AND v.follower_id = u.id) AS viewer_follows
+FROM
+	followers f
+	JOIN users u ON u.id = f.user_id
+WHERE
+	f.follower_id = $1
+	AND u.is_active = true
+ORDER BY
+	f.created_at DESC, u.id DESC
+LIMIT $3 OFFSET $4;`
+	return s.listUsers(ctx, query, userId, viewerId, pageQuery)
+}
+
+// GetFollowing returns one page of the people userId follows, newest first.
+// Follower is set on entries that viewerId follows.
+func (s *FollowerStore) GetFollowing(ctx context.Context, userId int64, viewerId int64, pageQuery *paginate.PaginatedQuery) ([]UserWithMetaData, error) {
+	query := `SELECT
+	u.id,
+	u.username,
+	u.created_at,
+	EXISTS (SELECT 1 FROM followers v WHERE v.user_id = $2 AND v.follower_id = u.id) AS viewer_follows
+FROM
+	followers f
+	JOIN users u ON u.id = f.follower_id
+WHERE
+	f.user_id = $1
+	AND u.is_active = true
+ORDER BY
+	f.created_at DESC, u.id DESC
+LIMIT $3 OFFSET $4;`
+	return s.listUsers(ctx, query, userId, viewerId, pageQuery)
+}
+
+func (s *FollowerStore) listUsers(ctx context.Context, query string, userId int64, viewerId int64, pageQuery *paginate.PaginatedQuery) ([]UserWithMetaData, error) {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
-	query := `DELETE FROM followers WHERE user_id = $1 AND follower_id = $2`
-	_, err := s.db.ExecContext(ctx, query, followeeId, followerId)
+	rows, err := s.db.QueryContext(ctx, query, userId, viewerId, pageQuery.Limit, pageQuery.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	users := []UserWithMetaData{}
+	for rows.Next() {
+		var u UserWithMetaData
+		if err := rows.Scan(&u.ID, &u.Username, &u.CreatedAt, &u.Follower); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, nil
+}
+
+type FollowRequest struct {
+	User        User      `json:"user"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+// RequestFollow asks a private account to let requesterId follow it. Returns
+// ErrConflict when there already is a request or a follow.
+func (s *FollowerStore) RequestFollow(ctx context.Context, requesterId int64, targetId int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+		var following bool
+		query := `SELECT EXISTS (SELECT 1 FROM followers WHERE user_id = $1 AND follower_id = $2)`
+		if err := tx.QueryRowContext(ctx, query, requesterId, targetId).Scan(&following); err != nil {
+			return err
+		}
+		if following {
+			return ErrConflict
+		}
+		query = `INSERT INTO follow_requests (requester_id, target_id) VALUES ( $1 , $2 )`
+		if _, err := tx.ExecContext(ctx, query, requesterId, targetId); err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				return ErrConflict
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+// ApproveRequest turns requesterId's pending request into a follow of ownerId.
+func (s *FollowerStore) ApproveRequest(ctx context.Context, ownerId int64, requesterId int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := s.deleteRequest(ctx, tx, ownerId, requesterId); err != nil {
+			return err
+		}
+		return s.follow(ctx, tx, requesterId, ownerId)
+	})
+}
+
+func (s *FollowerStore) RejectRequest(ctx context.Context, ownerId int64, requesterId int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		return s.deleteRequest(ctx, tx, ownerId, requesterId)
+	})
+}
+
+func (s *FollowerStore) deleteRequest(ctx context.Context, tx *sql.Tx, ownerId int64, requesterId int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `DELETE FROM follow_requests WHERE requester_id = $1 AND target_id = $2`
+	res, err := tx.ExecContext(ctx, query, requesterId, ownerId)
+	if err != nil {
+		return err
+	}
+	rows_affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows_affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// acceptAll approves every request pending for ownerId.
+func (s *FollowerStore) acceptAll(ctx context.Context, tx *sql.Tx, ownerId int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `INSERT INTO followers (user_id, follower_id)
+	SELECT requester_id, target_id FROM follow_requests WHERE target_id = $1
+	ON CONFLICT DO NOTHING`
+	if _, err := tx.ExecContext(ctx, query, ownerId); err != nil {
+		return err
+	}
+	query = `INSERT INTO timelines (user_id, post_id, author_id, created_at)
+	SELECT fr.requester_id, p.id, p.user_id, p.created_at FROM follow_requests fr
+		JOIN posts p ON p.user_id = fr.target_id
+	WHERE fr.target_id = $1
+	ON CONFLICT DO NOTHING`
+	if _, err := tx.ExecContext(ctx, query, ownerId); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `DELETE FROM follow_requests WHERE target_id = $1`, ownerId)
 	return err
 }
+
+// GetIncomingRequests returns one page of the requests waiting on ownerId.
+func (s *FollowerStore) GetIncomingRequests(ctx context.Context, ownerId int64, pageQuery *paginate.PaginatedQuery) ([]FollowRequest, error) {
+	query := `SELECT u.id , u.username , u.created_at , fr.created_at FROM follow_requests fr
+	JOIN users u ON u.id = fr.requester_id
+	WHERE fr.target_id = $1
+	ORDER BY fr.created_at DESC, u.id DESC
+	LIMIT $2 OFFSET $3`
+	return s.listRequests(ctx, query, ownerId, pageQuery)
+}
+
+// GetOutgoingRequests returns one page of the requests requesterId is waiting on.
+func (s *FollowerStore) GetOutgoingRequests(ctx context.Context, requesterId int64, pageQuery *paginate.PaginatedQuery) ([]FollowRequest, error) {
+	query := `SELECT u.id , u.username , u.created_at , fr.created_at FROM follow_requests fr
+	JOIN users u ON u.id = fr.target_id
+	WHERE fr.requester_id = $1
+	ORDER BY fr.created_at DESC, u.id DESC
+	LIMIT $2 OFFSET $3`
+	return s.listRequests(ctx, query, requesterId, pageQuery)
+}
+
+func (s *FollowerStore) listRequests(ctx context.Context, query string, userId int64, pageQuery *paginate.PaginatedQuery) ([]FollowRequest, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	rows, err := s.db.QueryContext(ctx, query, userId, pageQuery.Limit, pageQuery.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	requests := []FollowRequest{}
+	for rows.Next() {
+		var r FollowRequest
+		if err := rows.Scan(&r.User.ID, &r.User.Username, &r.User.CreatedAt, &r.RequestedAt); err != nil {
+			return nil, err
+		}
+		requests = append(requests, r)
+	}
+	return requests, nil
+}
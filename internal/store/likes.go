@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+type LikeStore struct {
+	db *sql.DB
+}
+
+func (s *LikeStore) LikePost(ctx context.Context, userId int64, postId int64) error {
+	return s.like(ctx,
+		`INSERT INTO post_likes (user_id,post_id) VALUES ( $1 , $2 )`,
+		userId, postId)
+}
+
+func (s *LikeStore) UnlikePost(ctx context.Context, userId int64, postId int64) error {
+	return s.unlike(ctx,
+		`DELETE FROM post_likes WHERE user_id = $1 AND post_id = $2`,
+		userId, postId)
+}
+
+func (s *LikeStore) LikeComment(ctx context.Context, userId int64, commentId int64) error {
+	return s.like(ctx,
+		`INSERT INTO comment_likes (user_id,comment_id) VALUES ( $1 , $2 )`,
+		userId, commentId)
+}
+
+func (s *LikeStore) UnlikeComment(ctx context.Context, userId int64, commentId int64) error {
+	return s.unlike(ctx,
+		`DELETE FROM comment_likes WHERE user_id = $1 AND comment_id = $2`,
+		userId, commentId)
+}
+
+func (s *LikeStore) HasLikedPost(ctx context.Context, userId int64, postId int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `SELECT EXISTS (SELECT 1 FROM post_likes WHERE user_id = $1 AND post_id = $2)`
+	var liked bool
+	if err := s.db.QueryRowContext(ctx, query, userId, postId).Scan(&liked); err != nil {
+		return false, err
+	}
+	return liked, nil
+}
+
+// like records the like, triggers on the likes tables keep the counters in
+// step. Returns ErrConflict when the user already liked the item.
+func (s *LikeStore) like(ctx context.Context, insertQuery string, userId int64, itemId int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	if _, err := s.db.ExecContext(ctx, insertQuery, userId, itemId); err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return ErrConflict
+		}
+		return err
+	}
+	return nil
+}
+
+// unlike removes the like. Returns ErrNotFound when the user had not liked
+// the item.
+func (s *LikeStore) unlike(ctx context.Context, deleteQuery string, userId int64, itemId int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	res, err := s.db.ExecContext(ctx, deleteQuery, userId, itemId)
+	if err != nil {
+		return err
+	}
+	rows_affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows_affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
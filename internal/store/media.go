@@ -0,0 +1,201 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// Media is an uploaded image. The files live in the blob store, the keys are
+// kept so they can be removed when the row goes away.
+type Media struct {
+	ID           int64  `json:"id"`
+	UserID       int64  `json:"user_id"`
+	Key          string `json:"-"`
+	URL          string `json:"url"`
+	ThumbnailKey string `json:"-"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	ContentType  string `json:"content_type"`
+	Size         int64  `json:"size"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	CreatedAt    string `json:"created_at"`
+}
+
+const mediaColumns = `m.id , m.user_id , m.key , m.url , m.thumbnail_key , m.thumbnail_url ,
+	m.content_type , m.size , m.width , m.height , m.created_at`
+
+func scanMedia(row interface{ Scan(...any) error }, m *Media) error {
+	return row.Scan(&m.ID, &m.UserID, &m.Key, &m.URL, &m.ThumbnailKey, &m.ThumbnailURL,
+		&m.ContentType, &m.Size, &m.Width, &m.Height, &m.CreatedAt)
+}
+
+// deleteMedia deletes the media rows matching where and returns them, so
+// the caller can remove their files once the transaction has committed.
+// Cascades only take the rows, never the files.
+func deleteMedia(ctx context.Context, tx *sql.Tx, where string, args ...any) ([]Media, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `DELETE FROM media m WHERE ` + where + ` RETURNING ` + mediaColumns
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	media := []Media{}
+	for rows.Next() {
+		var m Media
+		if err := scanMedia(rows, &m); err != nil {
+			return nil, err
+		}
+		media = append(media, m)
+	}
+	return media, rows.Err()
+}
+
+type MediaStore struct {
+	db *sql.DB
+}
+
+func (s *MediaStore) create(ctx context.Context, tx *sql.Tx, m *Media) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `INSERT INTO media (user_id , key , url , thumbnail_key , thumbnail_url , content_type , size , width , height)
+	VALUES ( $1 , $2 , $3 , $4 , $5 , $6 , $7 , $8 , $9 ) RETURNING id , created_at`
+	return tx.QueryRowContext(ctx, query, m.UserID, m.Key, m.URL, m.ThumbnailKey, m.ThumbnailURL,
+		m.ContentType, m.Size, m.Width, m.Height).Scan(&m.ID, &m.CreatedAt)
+}
+
+// SetAvatar makes m the user's avatar and returns the avatar it replaced, if
+// any, so the caller can remove its files.
+func (s *MediaStore) SetAvatar(ctx context.Context, m *Media) (*Media, error) {
+	var previous *Media
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := s.create(ctx, tx, m); err != nil {
+			return err
+		}
+		old, err := s.clearAvatar(ctx, tx, m.UserID)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		previous = old
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+		res, err := tx.ExecContext(ctx, `UPDATE users SET avatar_id = $1 WHERE id = $2`, m.ID, m.UserID)
+		if err != nil {
+			return err
+		}
+		rows_affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows_affected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return previous, nil
+}
+
+// DeleteAvatar removes the user's avatar and returns it.
+func (s *MediaStore) DeleteAvatar(ctx context.Context, userId int64) (*Media, error) {
+	var previous *Media
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		old, err := s.clearAvatar(ctx, tx, userId)
+		previous = old
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return previous, nil
+}
+
+func (s *MediaStore) clearAvatar(ctx context.Context, tx *sql.Tx, userId int64) (*Media, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `DELETE FROM media m USING users u
+	WHERE u.avatar_id = m.id AND u.id = $1
+	RETURNING ` + mediaColumns
+	var m Media
+	if err := scanMedia(tx.QueryRowContext(ctx, query, userId), &m); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (s *MediaStore) AddPostImage(ctx context.Context, postId int64, m *Media) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := s.create(ctx, tx, m); err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+		_, err := tx.ExecContext(ctx, `INSERT INTO post_images (post_id , media_id) VALUES ( $1 , $2 )`, postId, m.ID)
+		return err
+	})
+}
+
+func (s *MediaStore) GetPostImages(ctx context.Context, postId int64) ([]Media, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `SELECT ` + mediaColumns + `
+	FROM post_images pi
+	JOIN media m ON m.id = pi.media_id
+	WHERE pi.post_id = $1
+	ORDER BY pi.created_at ASC , m.id ASC`
+	rows, err := s.db.QueryContext(ctx, query, postId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	images := []Media{}
+	for rows.Next() {
+		var m Media
+		if err := scanMedia(rows, &m); err != nil {
+			return nil, err
+		}
+		images = append(images, m)
+	}
+	return images, rows.Err()
+}
+
+func (s *MediaStore) GetPostImage(ctx context.Context, postId int64, mediaId int64) (*Media, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `SELECT ` + mediaColumns + `
+	FROM post_images pi
+	JOIN media m ON m.id = pi.media_id
+	WHERE pi.post_id = $1 AND m.id = $2`
+	var m Media
+	if err := scanMedia(s.db.QueryRowContext(ctx, query, postId, mediaId), &m); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+// DeletePostImage removes an image from a post and returns it.
+func (s *MediaStore) DeletePostImage(ctx context.Context, postId int64, mediaId int64) (*Media, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `DELETE FROM media m USING post_images pi
+	WHERE pi.media_id = m.id AND pi.post_id = $1 AND m.id = $2
+	RETURNING ` + mediaColumns
+	var m Media
+	if err := scanMedia(s.db.QueryRowContext(ctx, query, postId, mediaId), &m); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &m, nil
+}
@@ -35,14 +35,34 @@ func (m *MockUserStore) Activate(ctx context.Context, t string) error {
 	return nil
 }
 
-func (m *MockUserStore) Delete(ctx context.Context, id int64) error {
+func (m *MockUserStore) Delete(ctx context.Context, id int64) ([]Media, error) {
+	return nil, nil
+}
+
+func (m *MockUserStore) createUserInvitation(ctx context.Context, tx *sql.Tx, token string, purpose string, exp time.Duration, userID int64) error {
 	return nil
 }
 
-func (m *MockUserStore) createUserInvitation(ctx context.Context, tx *sql.Tx, token string, exp time.Duration, userID int64) error {
+func (m *MockUserStore) CreatePasswordReset(ctx context.Context, email string, token string, exp time.Duration) (*User, error) {
+	return &User{Email: email}, nil
+}
+
+func (m *MockUserStore) ResetPassword(ctx context.Context, token string, password *PasswordType) error {
 	return nil
 }
 
 func (m *MockUserStore) SearchFriends(ctx context.Context, userId int64, friendQuery *paginate.FriendPaginateQuery) ([]UserWithMetaData, error) {
 	return nil, nil
 }
+
+func (m *MockUserStore) GetStats(ctx context.Context, userId int64) (*UserStats, error) {
+	return &UserStats{}, nil
+}
+
+func (m *MockUserStore) SetPrivate(ctx context.Context, userId int64, private bool) error {
+	return nil
+}
+
+func (m *MockUserStore) UpdateProfile(ctx context.Context, user *User, cooldown time.Duration, reservation time.Duration) error {
+	return nil
+}
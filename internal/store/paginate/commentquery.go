@@ -0,0 +1,26 @@
+package paginate
+
+import "net/http"
+
+type CommentPaginateQuery struct {
+	PaginatedQuery
+	Sort string `json:"sort,omitempty" validate:"oneof=newest oldest most_liked"`
+}
+
+func (cq *CommentPaginateQuery) Parse(req *http.Request) error {
+	cq.SetDefaults()
+	if err := cq.PaginatedQuery.Parse(req); err != nil {
+		return err
+	}
+	qs := req.URL.Query()
+
+	if sort := qs.Get("sort"); sort != "" {
+		cq.Sort = sort
+	}
+	return nil
+}
+
+func (cq *CommentPaginateQuery) SetDefaults() {
+	cq.PaginatedQuery.SetDefaults()
+	cq.Sort = "newest"
+}
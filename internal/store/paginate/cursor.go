@@ -0,0 +1,42 @@
+package paginate
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks a position in a list ordered by (created_at, id). Ranked
+// lists are ordered by (score, id) instead, with scores computed as of AsOf.
+type Cursor struct {
+	CreatedAt time.Time  `json:"t"`
+	ID        int64      `json:"i"`
+	Score     float64    `json:"s,omitempty"`
+	AsOf      *time.Time `json:"a,omitempty"`
+}
+
+type CursorPage struct {
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// Encode returns the cursor as an opaque url safe string.
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
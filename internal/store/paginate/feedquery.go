@@ -1,18 +1,28 @@
 package paginate
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type PostPaginateQuery struct {
 	PaginatedQuery
-	Sort string   `json:"sort,omitempty" validate:"oneof=asc desc"`
-	Tags []string `json:"tags,omitempty" validate:"max=5"`
+	Sort   string     `json:"sort,omitempty" validate:"oneof=asc desc"`
+	Tags   []string   `json:"tags,omitempty" validate:"max=5"`
+	Mode   string     `json:"mode,omitempty" validate:"oneof=latest ranked"`
+	Debug  bool       `json:"debug,omitempty"`
+	Since  *time.Time `json:"since,omitempty"`
+	Until  *time.Time `json:"until,omitempty"`
+	After  *Cursor    `json:"-"`
+	Before *Cursor    `json:"-"`
 }
 
 func (pq *PostPaginateQuery) Parse(req *http.Request) error {
-
+	// page size falls back to the default when ?limit is left out
+	pq.PaginatedQuery.SetDefaults()
 	if err := pq.PaginatedQuery.Parse(req); err != nil {
 		return err
 	}
@@ -28,9 +38,83 @@ func (pq *PostPaginateQuery) Parse(req *http.Request) error {
 	} else {
 		pq.Tags = []string{}
 	}
+	if mode := qs.Get("mode"); mode != "" {
+		pq.Mode = mode
+	}
+	if debug := qs.Get("debug"); debug != "" {
+		d, err := strconv.ParseBool(debug)
+		if err != nil {
+			return err
+		}
+		pq.Debug = d
+	}
+	if since := qs.Get("since"); since != "" {
+		t, err := parseDate(since)
+		if err != nil {
+			return err
+		}
+		pq.Since = &t
+	}
+	if until := qs.Get("until"); until != "" {
+		t, err := parseDate(until)
+		if err != nil {
+			return err
+		}
+		pq.Until = &t
+	}
+	after, before := qs.Get("after"), qs.Get("before")
+	if after != "" && before != "" {
+		return errors.New("only one of after and before can be set")
+	}
+	if (after != "" || before != "") && qs.Get("offset") != "" {
+		return errors.New("offset can't be combined with a cursor")
+	}
+	if after != "" {
+		c, err := DecodeCursor(after)
+		if err != nil {
+			return err
+		}
+		pq.After = c
+	}
+	if before != "" {
+		if pq.Mode == "ranked" {
+			return errors.New("ranked feed can only be paged forward")
+		}
+		c, err := DecodeCursor(before)
+		if err != nil {
+			return err
+		}
+		pq.Before = c
+	}
 	return nil
 }
 
+// Seek returns the cursor to start from along with the comparison operator
+// and sort order to use on (created_at, id). Pages before a cursor are read
+// in reverse and must be flipped back by the caller.
+func (pq *PostPaginateQuery) Seek() (*Cursor, string, string) {
+	desc := pq.Sort == "desc"
+	if pq.Before != nil {
+		if desc {
+			return pq.Before, ">", "asc"
+		}
+		return pq.Before, "<", "desc"
+	}
+	if desc {
+		return pq.After, "<", "desc"
+	}
+	return pq.After, ">", "asc"
+}
+
 func (pq *PostPaginateQuery) SetDefaults() {
 	pq.Sort = "asc"
+	pq.Mode = "latest"
+}
+
+// parseDate accepts either a full RFC 3339 timestamp or a plain date.
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
 }
@@ -0,0 +1,73 @@
+package paginate
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type SearchPaginateQuery struct {
+	PaginatedQuery
+	Query string   `json:"q" validate:"required,max=200"`
+	Tags  []string `json:"tags,omitempty" validate:"max=5"`
+}
+
+func (sq *SearchPaginateQuery) Parse(req *http.Request) error {
+	sq.SetDefaults()
+	if err := sq.PaginatedQuery.Parse(req); err != nil {
+		return err
+	}
+	qs := req.URL.Query()
+
+	sq.Query = strings.TrimSpace(qs.Get("q"))
+	tags := qs.Get("tags")
+	if tags != "" {
+		sq.Tags = strings.Split(tags, ",")
+	}
+	return nil
+}
+
+func (sq *SearchPaginateQuery) SetDefaults() {
+	sq.PaginatedQuery.SetDefaults()
+	sq.Tags = []string{}
+}
+
+type UnifiedSearchQuery struct {
+	Query string   `json:"q" validate:"required,max=100"`
+	Mode  string   `json:"mode,omitempty" validate:"oneof=full prefix"`
+	Limit int      `json:"limit,omitempty" validate:"gte=1,lte=20"`
+	Types []string `json:"types,omitempty" validate:"min=1,dive,oneof=users posts tags"`
+}
+
+func (uq *UnifiedSearchQuery) Parse(req *http.Request) error {
+	uq.SetDefaults()
+	qs := req.URL.Query()
+
+	uq.Query = strings.TrimSpace(qs.Get("q"))
+	if mode := qs.Get("mode"); mode != "" {
+		uq.Mode = mode
+	}
+	if limit := qs.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return err
+		}
+		uq.Limit = l
+	}
+	if types := qs.Get("types"); types != "" {
+		uq.Types = strings.Split(types, ",")
+	}
+	return nil
+}
+
+func (uq *UnifiedSearchQuery) SetDefaults() {
+	uq.Mode = "full"
+	uq.Limit = 5
+	uq.Types = []string{"users", "posts", "tags"}
+}
+
+// Includes reports whether results of the given type were asked for.
+func (uq *UnifiedSearchQuery) Includes(kind string) bool {
+	return slices.Contains(uq.Types, kind)
+}
@@ -0,0 +1,38 @@
+package paginate
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type TrendingTagQuery struct {
+	Window time.Duration `json:"window,omitempty" validate:"min=1h,max=720h"`
+	Limit  int           `json:"limit,omitempty" validate:"gte=1,lte=50"`
+}
+
+func (tq *TrendingTagQuery) Parse(req *http.Request) error {
+	tq.SetDefaults()
+	qs := req.URL.Query()
+
+	if window := qs.Get("window"); window != "" {
+		w, err := time.ParseDuration(window)
+		if err != nil {
+			return err
+		}
+		tq.Window = w
+	}
+	if limit := qs.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return err
+		}
+		tq.Limit = l
+	}
+	return nil
+}
+
+func (tq *TrendingTagQuery) SetDefaults() {
+	tq.Window = 7 * 24 * time.Hour
+	tq.Limit = 10
+}
@@ -5,40 +5,131 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"slices"
 	"time"
 
 	"github.com/lib/pq"
 )
 
+const (
+	PostStatusDraft     = "draft"
+	PostStatusPublished = "published"
+	PostStatusArchived  = "archived"
+)
+
+// Who can read a post: everyone, the author's followers, everyone with the
+// link but left out of listings, or only the author.
+const (
+	PostVisibilityPublic    = "public"
+	PostVisibilityFollowers = "followers"
+	PostVisibilityUnlisted  = "unlisted"
+	PostVisibilityPrivate   = "private"
+)
+
 type Post struct {
-	ID        int64     `json:"id"`
-	Title     string    `json:"title"`
-	Content   string    `json:"content"`
-	UserId    int64     `json:"user_id"`
-	Tags      []string  `json:"tags"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at,omitempty"`
-	Comments  []Comment `json:"comments,omitempty"`
-	Version   int       `json:"version"`
-	User      User      `json:"user"`
+	ID          int64      `json:"id"`
+	Title       string     `json:"title"`
+	Content     string     `json:"content"`
+	UserId      int64      `json:"user_id"`
+	Tags        []string   `json:"tags"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at,omitempty"`
+	Comments    []Comment  `json:"comments,omitempty"`
+	Version     int        `json:"version"`
+	Status      string     `json:"status"`
+	Visibility  string     `json:"visibility"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	PublishAt   *time.Time `json:"publish_at,omitempty"`
+	Likes       int64      `json:"likes"`
+	Liked       bool       `json:"liked"`
+	User        User       `json:"user"`
 }
 
 type PostWithMetaData struct {
 	Post
-	CommentCount int64 `json:"comment_count"`
+	CommentCount int64           `json:"comment_count"`
+	Reactions    ReactionSummary `json:"reactions"`
+	Score        *ScoreBreakdown `json:"score,omitempty"`
+	Reason       *FeedReason     `json:"reason,omitempty"`
+	BookmarkedAt *time.Time      `json:"bookmarked_at,omitempty"`
+}
+
+// FeedReason says why a post is in someone's feed. A post can be there both
+// for its author and for its tags.
+type FeedReason struct {
+	Own            bool     `json:"own,omitempty"`
+	FollowedAuthor bool     `json:"followed_author"`
+	FollowedTags   []string `json:"followed_tags"`
 }
 
+// feedCandidates lists the posts of reader $1's feed once each: everything on
+// their timeline plus posts tagged with a tag they follow.
+const feedCandidates = `followed_tags AS (
+	SELECT COALESCE(array_agg(tag), '{}') AS tags FROM tag_followers WHERE user_id = $1
+),
+feed AS (
+	SELECT post_id, bool_or(on_timeline) AS on_timeline FROM (
+		SELECT t.post_id, TRUE AS on_timeline FROM timelines t WHERE t.user_id = $1
+		UNION ALL
+		SELECT p.id, FALSE FROM posts p, followed_tags ft WHERE p.tags && ft.tags
+	) sources
+	GROUP BY post_id
+)`
+
+// postVisibleTo is a condition on post p that holds when viewer, an SQL
+// expression, may open it: neither has blocked the other, the post isn't
+// private, and followers-only posts and posts of private accounts are only
+// shown to followers. Authors always see their own posts.
+func postVisibleTo(viewer string) string {
+	return notBlocked(viewer, "p.user_id") + `
+	AND ( p.user_id = ` + viewer + `
+		OR ( p.visibility <> 'private' AND (
+			( p.visibility <> 'followers'
+				AND NOT EXISTS (SELECT 1 FROM users pu WHERE pu.id = p.user_id AND pu.is_private) )
+			OR EXISTS (SELECT 1 FROM followers pf WHERE pf.user_id = ` + viewer + ` AND pf.follower_id = p.user_id) ) ) )`
+}
+
+// postListedTo narrows postVisibleTo to the posts that show up in listings,
+// leaving out unlisted posts of other authors.
+func postListedTo(viewer string) string {
+	return postVisibleTo(viewer) + `
+	AND ( p.visibility <> 'unlisted' OR p.user_id = ` + viewer + ` )`
+}
+
+// feedFilter keeps posts reader $1 may not see or has muted out of their
+// feed. Unlisted posts only come in through the timeline, not followed tags.
+var feedFilter = postVisibleTo("$1::bigint") + `
+	AND ( p.visibility <> 'unlisted' OR f.on_timeline )
+	AND ` + notMuted("$1::bigint", "p.user_id")
+
+// feedReason is selected alongside feedCandidates to fill in FeedReason.
+const feedReason = `p.user_id = $1 AS own,
+	f.on_timeline AND p.user_id <> $1 AS followed_author,
+	ARRAY(SELECT tf.tag FROM tag_followers tf WHERE tf.user_id = $1 AND tf.tag = ANY(p.tags) ORDER BY tf.tag) AS reason_tags`
+
 type PostStore struct {
 	db *sql.DB
 }
 
-func (s *PostStore) GetById(ctx context.Context, id int64) (*Post, error) {
-	query := `SELECT id,title,content,created_at,updated_at,tags,version from
-		 posts where id = $1`
+// GetById loads the post if viewerId may open it, see postVisibleTo, and
+// returns ErrNotFound otherwise so its existence isn't revealed.
+func (s *PostStore) GetById(ctx context.Context, id int64, viewerId int64) (*Post, error) {
+	return s.getById(ctx, `AND `+postVisibleTo("$2::bigint"), id, viewerId)
+}
+
+// GetByIdUnchecked skips the visibility rules, for callers that have
+// already made sure the user may act on the post, such as moderators.
+func (s *PostStore) GetByIdUnchecked(ctx context.Context, id int64) (*Post, error) {
+	return s.getById(ctx, "", id)
+}
+
+func (s *PostStore) getById(ctx context.Context, filter string, args ...any) (*Post, error) {
+	query := `SELECT p.id,p.user_id,p.title,p.content,p.created_at,p.updated_at,p.tags,p.version,p.status,p.visibility,p.published_at,p.publish_at,p.likes_count from
+		 posts p where p.id = $1 ` + filter
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 	var post Post
-	err := s.db.QueryRowContext(ctx, query, id).Scan(&post.ID, &post.Title, &post.Content, &post.CreatedAt, &post.UpdatedAt, pq.Array(&post.Tags), &post.Version)
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&post.ID, &post.UserId, &post.Title, &post.Content, &post.CreatedAt, &post.UpdatedAt, pq.Array(&post.Tags), &post.Version, &post.Status, &post.Visibility, &post.PublishedAt, &post.PublishAt, &post.Likes)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -50,10 +141,16 @@ func (s *PostStore) GetById(ctx context.Context, id int64) (*Post, error) {
 	return &post, nil
 }
 
-func (s *PostStore) GetUserFeed(ctx context.Context, userId int64, pageQuery *paginate.PostPaginateQuery) ([]PostWithMetaData, error) {
+// GetUserFeed returns one page of the reader's timeline merged with posts
+// from the tags they follow, seeking from the cursor in pageQuery rather than
+// skipping rows so that pages stay stable while new posts arrive.
+func (s *PostStore) GetUserFeed(ctx context.Context, userId int64, pageQuery *paginate.PostPaginateQuery) ([]PostWithMetaData, paginate.CursorPage, error) {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
-	query := `SELECT
+	cursorAt, cursorID := cursorArgs(pageQuery)
+	_, op, order := pageQuery.Seek()
+	query := `WITH ` + feedCandidates + `
+SELECT
 	p.id,
 	p.user_id,
 	p.title,
@@ -62,19 +159,21 @@ func (s *PostStore) GetUserFeed(ctx context.Context, userId int64, pageQuery *pa
 	p.updated_at,
 	p.version,
 	p.tags,
+	p.status,
+	p.visibility,
+	p.published_at,
+	p.likes_count,
+	EXISTS (SELECT 1 FROM post_likes pl WHERE pl.post_id = p.id AND pl.user_id = $1) AS liked,
 	u.username,
-	COUNT(c.id) AS comment_count
+	` + liveCommentCount + ` AS comment_count,
+	` + feedReason + `
 FROM
-	posts p
-	LEFT JOIN comments c ON p.id = c.post_id
+	feed f
+	JOIN posts p ON p.id = f.post_id
 	LEFT JOIN users u ON p.user_id = u.id
-	 JOIN followers f ON f.follower_id = p.user_id
-	 OR p.user_id = $1
 WHERE
-	(
-	f.user_id = $1 
-	OR p.user_id = $1
-	)
+	p.status = 'published'
+	AND ` + feedFilter + `
 	AND
 	(
 	p.title ILIKE  '%' || $2 || '%'
@@ -83,83 +182,389 @@ WHERE
 	)
 	AND
 	( p.tags @> $3 OR $3 = '{}' )
-GROUP BY
-	p.id, u.username
+	AND
+	( $5::timestamptz IS NULL OR (p.created_at, p.id) ` + op + ` ($5::timestamptz, $6::bigint) )
 ORDER BY
-	p.created_at ` + pageQuery.Sort + ` LIMIT $4 OFFSET $5;`
+	p.created_at ` + order + `, p.id ` + order + ` LIMIT $4;`
 
+	// one extra row tells us whether there is another page
 	rows, err := s.db.QueryContext(ctx, query, userId,
-		pageQuery.Search, pq.Array(pageQuery.Tags),
-		pageQuery.Limit,
-		pageQuery.Offset)
+		pageQuery.Search, pq.Array(NormalizeTags(pageQuery.Tags)),
+		pageQuery.Limit+1,
+		cursorAt, cursorID)
 	if err != nil {
-		return nil, err
+		return nil, paginate.CursorPage{}, err
 	}
 	defer rows.Close()
 	feed := []PostWithMetaData{}
 	for rows.Next() {
 		var p PostWithMetaData
+		var reason FeedReason
 		err := rows.Scan(&p.ID, &p.UserId, &p.Title,
 			&p.Content, &p.CreatedAt, &p.UpdatedAt, &p.Version,
-			pq.Array(&p.Tags),
-			&p.User.Username, &p.CommentCount)
+			pq.Array(&p.Tags), &p.Status, &p.Visibility, &p.PublishedAt,
+			&p.Likes, &p.Liked,
+			&p.User.Username, &p.CommentCount,
+			&reason.Own, &reason.FollowedAuthor, pq.Array(&reason.FollowedTags))
 		if err != nil {
-			return nil, err
+			return nil, paginate.CursorPage{}, err
 		}
+		p.Reason = &reason
 		feed = append(feed, p)
 	}
-	return feed, nil
+	feed, page := cursorPage(feed, pageQuery)
+	return feed, page, nil
 }
 
-func (s *PostStore) Delete(ctx context.Context, postID int64) error {
-	query := `DELETE FROM posts where id = $1`
+// GetPublished lists published posts for anyone to read, newest or oldest
+// first, narrowed to a single author unless authorId is 0.
+func (s *PostStore) GetPublished(ctx context.Context, viewerId int64, authorId int64, pageQuery *paginate.PostPaginateQuery) ([]PostWithMetaData, paginate.CursorPage, error) {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
-	query_result, err := s.db.ExecContext(ctx, query, postID)
+	cursorAt, cursorID := cursorArgs(pageQuery)
+	_, op, order := pageQuery.Seek()
+	query := `SELECT
+	p.id,
+	p.user_id,
+	p.title,
+	p.content,
+	p.created_at,
+	p.updated_at,
+	p.version,
+	p.tags,
+	p.status,
+	p.visibility,
+	p.published_at,
+	p.likes_count,
+	EXISTS (SELECT 1 FROM post_likes pl WHERE pl.post_id = p.id AND pl.user_id = $1) AS liked,
+	u.username,
+	` + liveCommentCount + ` AS comment_count
+FROM
+	posts p
+	LEFT JOIN users u ON p.user_id = u.id
+WHERE
+	p.status = 'published'
+	AND ( $7::bigint = 0 OR p.user_id = $7::bigint )
+	AND ` + postListedTo("$1::bigint") + `
+	AND
+	(
+	p.title ILIKE  '%' || $2 || '%'
+	OR
+	p.content ILIKE  '%' || $2 || '%'
+	)
+	AND
+	( p.tags @> $3 OR $3 = '{}' )
+	AND ( $8::timestamptz IS NULL OR p.created_at >= $8::timestamptz )
+	AND ( $9::timestamptz IS NULL OR p.created_at < $9::timestamptz )
+	AND
+	( $5::timestamptz IS NULL OR (p.created_at, p.id) ` + op + ` ($5::timestamptz, $6::bigint) )
+ORDER BY
+	p.created_at ` + order + `, p.id ` + order + ` LIMIT $4;`
+
+	rows, err := s.db.QueryContext(ctx, query, viewerId,
+		pageQuery.Search, pq.Array(NormalizeTags(pageQuery.Tags)),
+		pageQuery.Limit+1,
+		cursorAt, cursorID,
+		authorId,
+		pageQuery.Since, pageQuery.Until)
 	if err != nil {
-		return err
+		return nil, paginate.CursorPage{}, err
 	}
-	rows_affected, err := query_result.RowsAffected()
+	defer rows.Close()
+	posts := []PostWithMetaData{}
+	for rows.Next() {
+		var p PostWithMetaData
+		err := rows.Scan(&p.ID, &p.UserId, &p.Title,
+			&p.Content, &p.CreatedAt, &p.UpdatedAt, &p.Version,
+			pq.Array(&p.Tags), &p.Status, &p.Visibility, &p.PublishedAt,
+			&p.Likes, &p.Liked,
+			&p.User.Username, &p.CommentCount)
+		if err != nil {
+			return nil, paginate.CursorPage{}, err
+		}
+		posts = append(posts, p)
+	}
+	posts, page := cursorPage(posts, pageQuery)
+	return posts, page, nil
+}
+
+func cursorArgs(pageQuery *paginate.PostPaginateQuery) (*time.Time, *int64) {
+	cursor, _, _ := pageQuery.Seek()
+	if cursor == nil {
+		return nil, nil
+	}
+	return &cursor.CreatedAt, &cursor.ID
+}
+
+// cursorPage trims the extra row fetched by a keyset query, restores the
+// display order of backward pages and works out the cursors around the page.
+func cursorPage(posts []PostWithMetaData, pageQuery *paginate.PostPaginateQuery) ([]PostWithMetaData, paginate.CursorPage) {
+	return cursorPageBy(posts, pageQuery, func(p PostWithMetaData) paginate.Cursor {
+		return paginate.Cursor{CreatedAt: p.CreatedAt, ID: p.ID}
+	})
+}
+
+// cursorPageBy is cursorPage for lists that are not ordered by the posts'
+// own (created_at, id); key returns the sort key of a row.
+func cursorPageBy(posts []PostWithMetaData, pageQuery *paginate.PostPaginateQuery, key func(PostWithMetaData) paginate.Cursor) ([]PostWithMetaData, paginate.CursorPage) {
+	page := paginate.CursorPage{}
+	hasMore := len(posts) > pageQuery.Limit
+	if hasMore {
+		posts = posts[:pageQuery.Limit]
+	}
+	if pageQuery.Before != nil {
+		slices.Reverse(posts)
+	}
+	if len(posts) == 0 {
+		return posts, page
+	}
+	first := key(posts[0])
+	last := key(posts[len(posts)-1])
+	if pageQuery.Before != nil {
+		page.NextCursor = last.Encode()
+		if hasMore {
+			page.PrevCursor = first.Encode()
+		}
+		return posts, page
+	}
+	if hasMore {
+		page.NextCursor = last.Encode()
+	}
+	if pageQuery.After != nil {
+		page.PrevCursor = first.Encode()
+	}
+	return posts, page
+}
+
+// Delete removes a post along with its images and returns the images, whose
+// files the caller still has to remove.
+func (s *PostStore) Delete(ctx context.Context, postID int64) ([]Media, error) {
+	var images []Media
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		media, err := deleteMedia(ctx, tx, `m.id IN (SELECT media_id FROM post_images WHERE post_id = $1)`, postID)
+		if err != nil {
+			return err
+		}
+		images = media
+		query := `DELETE FROM posts where id = $1`
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+		query_result, err := tx.ExecContext(ctx, query, postID)
+		if err != nil {
+			return err
+		}
+		rows_affected, err := query_result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows_affected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if rows_affected == 0 {
-		return ErrNotFound
+	return images, nil
+}
+func (s *PostStore) Create(ctx context.Context, post *Post) error {
+	query := `INSERT INTO posts (content,title,user_id,tags,status,published_at,publish_at,visibility) 
+		values ($1 , $2 , $3 , $4 , $5::varchar ,
+		 CASE WHEN $5::varchar = 'published' THEN NOW() END , $6 , $7)
+		 RETURNING id,created_at , updated_at , published_at`
+	if post.Status == "" {
+		post.Status = PostStatusPublished
+	}
+	if post.Visibility == "" {
+		post.Visibility = PostVisibilityPublic
+	}
+	post.Tags = NormalizeTags(post.Tags)
+	// the post goes onto the timelines right away, the feed only shows it
+	// once it is published
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+		err := tx.QueryRowContext(ctx,
+			query,
+			post.Content,
+			post.Title,
+			post.UserId,
+			pq.Array(&post.Tags),
+			post.Status,
+			post.PublishAt,
+			post.Visibility).Scan(&post.ID, &post.CreatedAt, &post.UpdatedAt, &post.PublishedAt)
+		if err != nil {
+			return err
+		}
+		timelines := &TimelineStore{s.db}
+		return timelines.fanOut(ctx, tx, post)
+	})
+}
+
+func (s *PostStore) Update(ctx context.Context, post *Post) error {
+	// keep the replaced title and content as a revision
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		revisions := &RevisionStore{s.db}
+		if err := revisions.create(ctx, tx, post.ID, post.Version); err != nil {
+			return err
+		}
+		return s.update(ctx, tx, post)
+	})
+}
+
+func (s *PostStore) update(ctx context.Context, tx *sql.Tx, post *Post) error {
+	query := `UPDATE 
+	posts SET title = $1 , content = $2 , visibility = $5 , version = version + 1 , updated_at = NOW() WHERE
+	id = $3 AND version = $4 RETURNING version , updated_at`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	err := tx.QueryRowContext(ctx, query, post.Title, post.Content, post.ID, post.Version, post.Visibility).Scan(&post.Version, &post.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrVersionMismatch
+		default:
+			return err
+		}
 	}
 	return nil
 }
-func (s *PostStore) Create(ctx context.Context, post *Post) error {
-	query := `INSERT INTO posts (content,title,user_id,tags) 
-		values ($1 , $2 , $3 , $4)
-		 RETURNING id,created_at , updated_at`
+
+// UpdateStatus moves the post to post.Status. Returns ErrConflict when the
+// post is already in that status.
+func (s *PostStore) UpdateStatus(ctx context.Context, post *Post) error {
+	query := `UPDATE posts SET status = $1::varchar ,
+	published_at = CASE
+		WHEN $1::varchar = 'published' THEN COALESCE(published_at, NOW())
+		WHEN $1::varchar = 'draft' THEN NULL
+		ELSE published_at
+	END,
+	publish_at = NULL,
+	updated_at = NOW()
+	WHERE id = $2 AND status <> $1::varchar RETURNING published_at , updated_at`
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
-	err := s.db.QueryRowContext(ctx,
-		query,
-		post.Content,
-		post.Title,
-		post.UserId,
-		pq.Array(&post.Tags)).Scan(&post.ID, &post.CreatedAt, &post.UpdatedAt)
+	err := s.db.QueryRowContext(ctx, query, post.Status, post.ID).Scan(&post.PublishedAt, &post.UpdatedAt)
+	post.PublishAt = nil
 	if err != nil {
-		return err
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrConflict
+		default:
+			return err
+		}
 	}
 	return nil
 }
 
-func (s *PostStore) Update(ctx context.Context, post *Post) error {
-	query := `UPDATE 
-	posts SET title = $1 , content = $2 , version = version + 1 WHERE
-	id = $3 AND version = $4 RETURNING version`
+func (s *PostStore) GetDrafts(ctx context.Context, userId int64, pageQuery *paginate.PostPaginateQuery) ([]Post, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `SELECT
+	p.id,
+	p.user_id,
+	p.title,
+	p.content,
+	p.created_at,
+	p.updated_at,
+	p.version,
+	p.tags,
+	p.status,
+	p.visibility,
+	p.publish_at
+FROM
+	posts p
+WHERE
+	p.user_id = $1
+	AND p.status = 'draft'
+	AND
+	(
+	p.title ILIKE  '%' || $2 || '%'
+	OR
+	p.content ILIKE  '%' || $2 || '%'
+	)
+	AND
+	( p.tags @> $3 OR $3 = '{}' )
+ORDER BY
+	p.created_at ` + pageQuery.Sort + ` LIMIT $4 OFFSET $5;`
+
+	rows, err := s.db.QueryContext(ctx, query, userId,
+		pageQuery.Search, pq.Array(NormalizeTags(pageQuery.Tags)),
+		pageQuery.Limit,
+		pageQuery.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	drafts := []Post{}
+	for rows.Next() {
+		var p Post
+		err := rows.Scan(&p.ID, &p.UserId, &p.Title,
+			&p.Content, &p.CreatedAt, &p.UpdatedAt, &p.Version,
+			pq.Array(&p.Tags), &p.Status, &p.Visibility, &p.PublishAt)
+		if err != nil {
+			return nil, err
+		}
+		drafts = append(drafts, p)
+	}
+	return drafts, nil
+}
+
+// Schedule sets or clears (nil post.PublishAt) the time a draft goes live.
+// Returns ErrConflict when the post is no longer a draft.
+func (s *PostStore) Schedule(ctx context.Context, post *Post) error {
+	query := `UPDATE posts SET publish_at = $1 , updated_at = NOW()
+	WHERE id = $2 AND status = 'draft' RETURNING updated_at`
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
-	err := s.db.QueryRowContext(ctx, query, post.Title, post.Content, post.ID, post.Version).Scan(&post.Version)
+	err := s.db.QueryRowContext(ctx, query, post.PublishAt, post.ID).Scan(&post.UpdatedAt)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
-			return ErrNotFound
+			return ErrConflict
 		default:
 			return err
 		}
 	}
 	return nil
 }
+
+// PublishDue publishes up to limit drafts whose publish_at has passed.
+// Rows are claimed with SKIP LOCKED so several replicas can run this
+// concurrently without publishing the same post twice.
+func (s *PostStore) PublishDue(ctx context.Context, limit int) ([]Post, error) {
+	query := `UPDATE posts p SET status = 'published' ,
+	published_at = p.publish_at ,
+	publish_at = NULL ,
+	updated_at = NOW()
+FROM
+	users u
+WHERE
+	u.id = p.user_id
+	AND p.id IN (
+		SELECT id FROM posts
+		WHERE status = 'draft' AND publish_at <= NOW()
+		ORDER BY publish_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	)
+RETURNING p.id , p.user_id , p.title , p.status , p.visibility , p.published_at , u.username`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	rows, err := s.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	published := []Post{}
+	for rows.Next() {
+		var p Post
+		err := rows.Scan(&p.ID, &p.UserId, &p.Title, &p.Status, &p.Visibility, &p.PublishedAt, &p.User.Username)
+		if err != nil {
+			return nil, err
+		}
+		p.User.ID = p.UserId
+		published = append(published, p)
+	}
+	return published, rows.Err()
+}
@@ -0,0 +1,146 @@
+package store
+
+import (
+	"Blog/internal/store/paginate"
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// RankingConfig weighs the signals of the ranked feed. Recency decays by
+// half every HalfLife and only posts younger than Window are considered.
+type RankingConfig struct {
+	RecencyWeight  float64
+	CommentWeight  float64
+	LikeWeight     float64
+	AffinityWeight float64
+	HalfLife       time.Duration
+	Window         time.Duration
+}
+
+type ScoreBreakdown struct {
+	Recency  float64 `json:"recency"`
+	Comments float64 `json:"comments"`
+	Likes    float64 `json:"likes"`
+	Affinity float64 `json:"affinity"`
+	Total    float64 `json:"total"`
+}
+
+// GetRankedFeed returns one page of the reader's feed ordered by score.
+// Scores are computed as of the time the first page was requested, carried
+// along in the cursor, and only count activity up to then, so pages don't
+// shift while the reader scrolls.
+func (s *PostStore) GetRankedFeed(ctx context.Context, userId int64, pageQuery *paginate.PostPaginateQuery, cfg RankingConfig) ([]PostWithMetaData, paginate.CursorPage, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	asOf := time.Now().UTC().Truncate(time.Second)
+	var cursorScore *float64
+	var cursorID *int64
+	if pageQuery.After != nil {
+		if pageQuery.After.AsOf != nil {
+			asOf = *pageQuery.After.AsOf
+		}
+		cursorScore = &pageQuery.After.Score
+		cursorID = &pageQuery.After.ID
+	}
+	query := `WITH ` + feedCandidates + `,
+affinity AS (
+	SELECT ap.user_id AS author_id, COUNT(*) AS interactions
+	FROM (
+		SELECT post_id FROM comments WHERE user_id = $1 AND created_at <= $2
+		UNION ALL
+		SELECT post_id FROM post_likes WHERE user_id = $1 AND created_at <= $2
+	) i
+	JOIN posts ap ON ap.id = i.post_id
+	GROUP BY ap.user_id
+),
+candidates AS (
+	SELECT
+		p.id, p.user_id, p.title, p.content, p.created_at, p.updated_at,
+		p.version, p.tags, p.status, p.visibility, p.published_at, p.likes_count,
+		EXISTS (SELECT 1 FROM post_likes pl WHERE pl.post_id = p.id AND pl.user_id = $1) AS liked,
+		u.username,
+		` + liveCommentCount + ` AS comment_count,
+		(SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id AND c.deleted_at IS NULL AND c.created_at <= $2) AS ranked_comments,
+		(SELECT COUNT(*) FROM post_likes pl WHERE pl.post_id = p.id AND pl.created_at <= $2) AS ranked_likes,
+		COALESCE(a.interactions, 0) AS interactions,
+		` + feedReason + `
+	FROM
+		feed f
+		JOIN posts p ON p.id = f.post_id
+		LEFT JOIN users u ON p.user_id = u.id
+		LEFT JOIN affinity a ON a.author_id = p.user_id
+	WHERE
+		p.status = 'published'
+		AND ` + feedFilter + `
+		AND p.created_at <= $2
+		AND p.created_at > $2 - make_interval(secs => $13::float8)
+		AND
+		(
+		p.title ILIKE  '%' || $3 || '%'
+		OR
+		p.content ILIKE  '%' || $3 || '%'
+		)
+		AND
+		( p.tags @> $4 OR $4 = '{}' )
+),
+scored AS (
+	SELECT *,
+		$7::float8 * EXP(-LN(2::float8) * EXTRACT(EPOCH FROM ($2 - created_at))::float8 / $12::float8) AS recency_score,
+		$8::float8 * LN(1 + ranked_comments::float8) AS comment_score,
+		$9::float8 * LN(1 + ranked_likes::float8) AS like_score,
+		$10::float8 * LN(1 + interactions::float8) AS affinity_score
+	FROM candidates
+)
+SELECT
+	id, user_id, title, content, created_at, updated_at, version, tags, status, visibility,
+	published_at, likes_count, liked, username, comment_count,
+	own, followed_author, reason_tags,
+	recency_score, comment_score, like_score, affinity_score,
+	recency_score + comment_score + like_score + affinity_score AS score
+FROM scored
+WHERE
+	$5::float8 IS NULL
+	OR (recency_score + comment_score + like_score + affinity_score, id) < ($5::float8, $6::bigint)
+ORDER BY score DESC, id DESC
+LIMIT $11;`
+
+	rows, err := s.db.QueryContext(ctx, query, userId, asOf,
+		pageQuery.Search, pq.Array(NormalizeTags(pageQuery.Tags)),
+		cursorScore, cursorID,
+		cfg.RecencyWeight, cfg.CommentWeight, cfg.LikeWeight, cfg.AffinityWeight,
+		pageQuery.Limit+1,
+		cfg.HalfLife.Seconds(), cfg.Window.Seconds())
+	if err != nil {
+		return nil, paginate.CursorPage{}, err
+	}
+	defer rows.Close()
+	feed := []PostWithMetaData{}
+	for rows.Next() {
+		var p PostWithMetaData
+		var score ScoreBreakdown
+		var reason FeedReason
+		err := rows.Scan(&p.ID, &p.UserId, &p.Title,
+			&p.Content, &p.CreatedAt, &p.UpdatedAt, &p.Version,
+			pq.Array(&p.Tags), &p.Status, &p.Visibility, &p.PublishedAt,
+			&p.Likes, &p.Liked,
+			&p.User.Username, &p.CommentCount,
+			&reason.Own, &reason.FollowedAuthor, pq.Array(&reason.FollowedTags),
+			&score.Recency, &score.Comments, &score.Likes, &score.Affinity, &score.Total)
+		if err != nil {
+			return nil, paginate.CursorPage{}, err
+		}
+		p.Score = &score
+		p.Reason = &reason
+		feed = append(feed, p)
+	}
+	page := paginate.CursorPage{}
+	if len(feed) > pageQuery.Limit {
+		feed = feed[:pageQuery.Limit]
+		last := feed[len(feed)-1]
+		next := paginate.Cursor{CreatedAt: last.CreatedAt, ID: last.ID, Score: last.Score.Total, AsOf: &asOf}
+		page.NextCursor = next.Encode()
+	}
+	return feed, page, nil
+}
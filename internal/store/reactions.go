@@ -0,0 +1,95 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+type ReactionSummary struct {
+	Counts map[string]int64 `json:"counts"`
+	Mine   []string         `json:"mine"`
+}
+
+func NewReactionSummary() ReactionSummary {
+	return ReactionSummary{
+		Counts: map[string]int64{},
+		Mine:   []string{},
+	}
+}
+
+type ReactionStore struct {
+	db *sql.DB
+}
+
+func (s *ReactionStore) Add(ctx context.Context, userId int64, postId int64, reaction string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `INSERT INTO post_reactions (user_id,post_id,reaction) VALUES ( $1 , $2 , $3 )`
+	_, err := s.db.ExecContext(ctx, query, userId, postId, reaction)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return ErrConflict
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *ReactionStore) Remove(ctx context.Context, userId int64, postId int64, reaction string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `DELETE FROM post_reactions WHERE user_id = $1 AND post_id = $2 AND reaction = $3`
+	res, err := s.db.ExecContext(ctx, query, userId, postId, reaction)
+	if err != nil {
+		return err
+	}
+	rows_affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows_affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetSummaries returns the reaction counts of each post along with the
+// reactions viewerId left on it. Every requested post gets an entry.
+func (s *ReactionStore) GetSummaries(ctx context.Context, viewerId int64, postIds []int64) (map[int64]ReactionSummary, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `SELECT post_id , reaction , COUNT(*) , BOOL_OR(user_id = $1)
+	FROM post_reactions
+	WHERE post_id = ANY($2)
+	GROUP BY post_id , reaction
+	ORDER BY post_id , COUNT(*) DESC`
+	summaries := map[int64]ReactionSummary{}
+	for _, id := range postIds {
+		summaries[id] = NewReactionSummary()
+	}
+	rows, err := s.db.QueryContext(ctx, query, viewerId, pq.Array(postIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			postId   int64
+			reaction string
+			count    int64
+			mine     bool
+		)
+		if err := rows.Scan(&postId, &reaction, &count, &mine); err != nil {
+			return nil, err
+		}
+		summary := summaries[postId]
+		summary.Counts[reaction] = count
+		if mine {
+			summary.Mine = append(summary.Mine, reaction)
+		}
+		summaries[postId] = summary
+	}
+	return summaries, nil
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type PostRevision struct {
+	ID        int64     `json:"id"`
+	PostID    int64     `json:"post_id"`
+	Version   int       `json:"version"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type RevisionStore struct {
+	db *sql.DB
+}
+
+func (s *RevisionStore) GetByPostID(ctx context.Context, postID int64) ([]PostRevision, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `SELECT id , post_id , version , title , content , created_at
+	FROM post_revisions WHERE post_id = $1 ORDER BY version DESC`
+	rows, err := s.db.QueryContext(ctx, query, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	revisions := []PostRevision{}
+	for rows.Next() {
+		var r PostRevision
+		if err := rows.Scan(&r.ID, &r.PostID, &r.Version, &r.Title, &r.Content, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+	return revisions, nil
+}
+
+func (s *RevisionStore) GetByVersion(ctx context.Context, postID int64, version int) (*PostRevision, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `SELECT id , post_id , version , title , content , created_at
+	FROM post_revisions WHERE post_id = $1 AND version = $2`
+	var r PostRevision
+	err := s.db.QueryRowContext(ctx, query, postID, version).Scan(&r.ID, &r.PostID, &r.Version, &r.Title, &r.Content, &r.CreatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &r, nil
+}
+
+// create snapshots the post as it is at version, locking the row so that a
+// concurrent update of the same version fails the guard instead. Returns
+// ErrVersionMismatch when the post is no longer at version.
+func (s *RevisionStore) create(ctx context.Context, tx *sql.Tx, postID int64, version int) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `INSERT INTO post_revisions (post_id , version , title , content)
+	SELECT id , version , title , content FROM posts
+	WHERE id = $1 AND version = $2 FOR UPDATE`
+	res, err := tx.ExecContext(ctx, query, postID, version)
+	if err != nil {
+		return err
+	}
+	rows_affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows_affected == 0 {
+		return ErrVersionMismatch
+	}
+	return nil
+}
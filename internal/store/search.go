@@ -0,0 +1,316 @@
+package store
+
+import (
+	"Blog/internal/store/paginate"
+	"context"
+	"database/sql"
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/lib/pq"
+)
+
+type PostSearchResult struct {
+	PostWithMetaData
+	Rank             float64 `json:"rank"`
+	TitleHighlight   string  `json:"title_highlight"`
+	ContentHighlight string  `json:"content_highlight"`
+}
+
+// BuildTSQuery turns a user search into to_tsquery syntax. Words are ANDed
+// together, "quoted words" must appear next to each other and a trailing *
+// matches any word with that prefix. Anything but letters and digits is
+// dropped so user input can't inject tsquery operators.
+func BuildTSQuery(search string) string {
+	terms := []string{}
+	for i, part := range strings.Split(search, `"`) {
+		// odd parts sit between quotes
+		if i%2 == 1 {
+			words := []string{}
+			for _, word := range strings.Fields(part) {
+				if lexeme := sanitizeLexeme(word); lexeme != "" {
+					words = append(words, lexeme)
+				}
+			}
+			if len(words) > 0 {
+				terms = append(terms, "("+strings.Join(words, " <-> ")+")")
+			}
+			continue
+		}
+		for _, word := range strings.Fields(part) {
+			lexeme := sanitizeLexeme(word)
+			if lexeme == "" {
+				continue
+			}
+			if strings.HasSuffix(word, "*") {
+				lexeme += ":*"
+			}
+			terms = append(terms, lexeme)
+		}
+	}
+	return strings.Join(terms, " & ")
+}
+
+func sanitizeLexeme(word string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, word)
+}
+
+// ts_headline copies the text as is, so it marks matches with private use
+// characters and highlightHTML escapes the text before turning them into
+// <mark> tags. The characters are stripped from the text first so a post
+// can't bring its own.
+const (
+	highlightStart = "\uE000"
+	highlightStop  = "\uE001"
+)
+
+// highlightHTML returns a ts_headline result as HTML that is safe to render.
+func highlightHTML(headline string) string {
+	escaped := html.EscapeString(headline)
+	return strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>").Replace(escaped)
+}
+
+// Search ranks published posts against the search, weighting title matches
+// over content matches, and highlights where they matched.
+func (s *PostStore) Search(ctx context.Context, viewerId int64, searchQuery *paginate.SearchPaginateQuery) ([]PostSearchResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	tsquery := BuildTSQuery(searchQuery.Query)
+	if tsquery == "" {
+		return []PostSearchResult{}, nil
+	}
+	query := `SELECT
+	p.id,
+	p.user_id,
+	p.title,
+	p.content,
+	p.created_at,
+	p.updated_at,
+	p.version,
+	p.tags,
+	p.status,
+	p.visibility,
+	p.published_at,
+	p.likes_count,
+	EXISTS (SELECT 1 FROM post_likes pl WHERE pl.post_id = p.id AND pl.user_id = $1) AS liked,
+	u.username,
+	` + liveCommentCount + ` AS comment_count,
+	ts_rank(p.search_vector, q) AS rank,
+	ts_headline('english', translate(p.title, $6, ''), q, 'StartSel=' || $7 || ', StopSel=' || $8 || ', HighlightAll=true'),
+	ts_headline('english', translate(p.content, $6, ''), q, 'StartSel=' || $7 || ', StopSel=' || $8 || ', MaxFragments=2, MaxWords=30, MinWords=10')
+FROM
+	posts p
+	LEFT JOIN users u ON p.user_id = u.id,
+	to_tsquery('english', $2) q
+WHERE
+	p.status = 'published'
+	AND p.search_vector @@ q
+	AND ` + postListedTo("$1::bigint") + `
+	AND
+	( p.tags @> $3 OR $3 = '{}' )
+ORDER BY
+	rank DESC, p.id DESC
+LIMIT $4 OFFSET $5;`
+
+	rows, err := s.db.QueryContext(ctx, query, viewerId, tsquery,
+		pq.Array(NormalizeTags(searchQuery.Tags)), searchQuery.Limit, searchQuery.Offset,
+		highlightStart+highlightStop, highlightStart, highlightStop)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	results := []PostSearchResult{}
+	for rows.Next() {
+		var r PostSearchResult
+		err := rows.Scan(&r.ID, &r.UserId, &r.Title,
+			&r.Content, &r.CreatedAt, &r.UpdatedAt, &r.Version,
+			pq.Array(&r.Tags), &r.Status, &r.Visibility, &r.PublishedAt,
+			&r.Likes, &r.Liked,
+			&r.User.Username, &r.CommentCount,
+			&r.Rank, &r.TitleHighlight, &r.ContentHighlight)
+		if err != nil {
+			return nil, err
+		}
+		r.TitleHighlight = highlightHTML(r.TitleHighlight)
+		r.ContentHighlight = highlightHTML(r.ContentHighlight)
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+type SearchStore struct {
+	db *sql.DB
+}
+
+type PostSuggestion struct {
+	ID       int64  `json:"id"`
+	Title    string `json:"title"`
+	UserID   int64  `json:"user_id"`
+	Username string `json:"username"`
+}
+
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// likePattern builds a LIKE pattern matching the search either anywhere or,
+// in prefix mode, only at the start. Prefix patterns are lower cased to line
+// up with the lower(...) text_pattern_ops indexes.
+func likePattern(searchQuery *paginate.UnifiedSearchQuery) string {
+	search := likeEscaper.Replace(strings.ToLower(searchQuery.Query))
+	if searchQuery.Mode == "prefix" {
+		return search + "%"
+	}
+	return "%" + search + "%"
+}
+
+func (s *SearchStore) Users(ctx context.Context, viewerId int64, searchQuery *paginate.UnifiedSearchQuery) ([]User, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `SELECT
+	u.id,
+	u.username,
+	u.created_at
+FROM
+	users u
+WHERE
+	u.is_active = TRUE
+	AND lower(u.username) LIKE $1
+	AND ` + notBlocked("$3::bigint", "u.id") + `
+ORDER BY
+	length(u.username) ASC, u.username ASC
+LIMIT $2;`
+	if searchQuery.Mode == "full" {
+		query = `SELECT
+	u.id,
+	u.username,
+	u.created_at
+FROM
+	users u
+WHERE
+	u.is_active = TRUE
+	AND u.username ILIKE $1
+	AND ` + notBlocked("$3::bigint", "u.id") + `
+ORDER BY
+	similarity(u.username, $4) DESC, u.username ASC
+LIMIT $2;`
+	}
+	args := []any{likePattern(searchQuery), searchQuery.Limit, viewerId}
+	if searchQuery.Mode == "full" {
+		args = append(args, searchQuery.Query)
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	users := []User{}
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.ID, &u.Username, &u.CreatedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, nil
+}
+
+// Posts matches published posts by title prefix in prefix mode and against
+// the full-text vector otherwise.
+func (s *SearchStore) Posts(ctx context.Context, viewerId int64, searchQuery *paginate.UnifiedSearchQuery) ([]PostSuggestion, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `SELECT
+	p.id,
+	p.title,
+	p.user_id,
+	u.username
+FROM
+	posts p
+	JOIN users u ON p.user_id = u.id
+WHERE
+	p.status = 'published'
+	AND lower(p.title) LIKE $1
+	AND ` + postListedTo("$3::bigint") + `
+ORDER BY
+	p.likes_count DESC, p.id DESC
+LIMIT $2;`
+	arg := likePattern(searchQuery)
+	if searchQuery.Mode == "full" {
+		arg = BuildTSQuery(searchQuery.Query)
+		if arg == "" {
+			return []PostSuggestion{}, nil
+		}
+		query = `SELECT
+	p.id,
+	p.title,
+	p.user_id,
+	u.username
+FROM
+	posts p
+	JOIN users u ON p.user_id = u.id,
+	to_tsquery('english', $1) q
+WHERE
+	p.status = 'published'
+	AND p.search_vector @@ q
+	AND ` + postListedTo("$3::bigint") + `
+ORDER BY
+	ts_rank(p.search_vector, q) DESC, p.id DESC
+LIMIT $2;`
+	}
+	rows, err := s.db.QueryContext(ctx, query, arg, searchQuery.Limit, viewerId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	posts := []PostSuggestion{}
+	for rows.Next() {
+		var p PostSuggestion
+		if err := rows.Scan(&p.ID, &p.Title, &p.UserID, &p.Username); err != nil {
+			return nil, err
+		}
+		posts = append(posts, p)
+	}
+	return posts, nil
+}
+
+// Tags returns tags used on public published posts that match the search,
+// most used first. Counts come from tag_counts, which a trigger on posts
+// keeps current.
+func (s *SearchStore) Tags(ctx context.Context, searchQuery *paginate.UnifiedSearchQuery) ([]TagCount, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `SELECT
+	tag,
+	uses
+FROM
+	tag_counts
+WHERE
+	tag LIKE $1
+ORDER BY
+	uses DESC, tag ASC
+LIMIT $2;`
+	rows, err := s.db.QueryContext(ctx, query, likePattern(searchQuery), searchQuery.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tags := []TagCount{}
+	for rows.Next() {
+		var t TagCount
+		if err := rows.Scan(&t.Tag, &t.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, nil
+}
@@ -0,0 +1,40 @@
+package store
+
+import "testing"
+
+func TestBuildTSQuery(t *testing.T) {
+	cases := []struct {
+		search string
+		want   string
+	}{
+		{"go postgres", "go & postgres"},
+		{`"full text" search`, "(full <-> text) & search"},
+		{"post*", "post:*"},
+		{"drop & | ! table:*", "drop & table:*"},
+		{`"" !!`, ""},
+	}
+	for _, c := range cases {
+		if got := BuildTSQuery(c.search); got != c.want {
+			t.Errorf("BuildTSQuery(%q) = %q; expected %q", c.search, got, c.want)
+		}
+	}
+}
+
+func TestHighlightHTML(t *testing.T) {
+	cases := []struct {
+		headline string
+		want     string
+	}{
+		{"plain " + highlightStart + "match" + highlightStop, "plain <mark>match</mark>"},
+		{
+			`<script>alert(1)</script> ` + highlightStart + "go" + highlightStop,
+			"&lt;script&gt;alert(1)&lt;/script&gt; <mark>go</mark>",
+		},
+		{`<img src=x onerror="alert(1)">`, "&lt;img src=x onerror=&#34;alert(1)&#34;&gt;"},
+	}
+	for _, c := range cases {
+		if got := highlightHTML(c.headline); got != c.want {
+			t.Errorf("highlightHTML(%q) = %q; expected %q", c.headline, got, c.want)
+		}
+	}
+}
@@ -13,6 +13,8 @@ var (
 	ErrConflict          = errors.New("already exists")
 	ErrDuplicateEmail    = errors.New("email already exists")
 	ErrDuplicateUsername = errors.New("username already exists")
+	ErrVersionMismatch   = errors.New("version mismatch")
+	ErrUsernameTooSoon   = errors.New("username was changed too recently")
 )
 
 const (
@@ -22,32 +24,118 @@ const (
 type Storage struct {
 	Posts interface {
 		Create(context.Context, *Post) error
-		GetById(context.Context, int64) (*Post, error)
-		Delete(context.Context, int64) error
+		GetById(context.Context, int64, int64) (*Post, error)
+		GetByIdUnchecked(context.Context, int64) (*Post, error)
+		Delete(context.Context, int64) ([]Media, error)
 		Update(context.Context, *Post) error
-		GetUserFeed(context.Context, int64, *paginate.PostPaginateQuery) ([]PostWithMetaData, error)
+		GetUserFeed(context.Context, int64, *paginate.PostPaginateQuery) ([]PostWithMetaData, paginate.CursorPage, error)
+		GetRankedFeed(context.Context, int64, *paginate.PostPaginateQuery, RankingConfig) ([]PostWithMetaData, paginate.CursorPage, error)
+		GetPublished(context.Context, int64, int64, *paginate.PostPaginateQuery) ([]PostWithMetaData, paginate.CursorPage, error)
+		Search(context.Context, int64, *paginate.SearchPaginateQuery) ([]PostSearchResult, error)
+		UpdateStatus(context.Context, *Post) error
+		GetDrafts(context.Context, int64, *paginate.PostPaginateQuery) ([]Post, error)
+		Schedule(context.Context, *Post) error
+		PublishDue(context.Context, int) ([]Post, error)
 	}
 	Users interface {
 		Create(context.Context, *sql.Tx, *User) error
 		GetUserById(context.Context, int64) (*User, error)
 		CreateAndInvite(context.Context, *User, string, time.Duration) error
-		createUserInvitation(context.Context, *sql.Tx, string, time.Duration, int64) error
+		createUserInvitation(context.Context, *sql.Tx, string, string, time.Duration, int64) error
 		Activate(context.Context, string) error
-		Delete(context.Context, int64) error
+		Delete(context.Context, int64) ([]Media, error)
 		GetUserByEmail(context.Context, string) (*User, error)
 		SearchFriends(context.Context, int64, *paginate.FriendPaginateQuery) ([]UserWithMetaData, error)
+		GetStats(context.Context, int64) (*UserStats, error)
+		SetPrivate(context.Context, int64, bool) error
+		UpdateProfile(context.Context, *User, time.Duration, time.Duration) error
+		CreatePasswordReset(context.Context, string, string, time.Duration) (*User, error)
+		ResetPassword(context.Context, string, *PasswordType) error
 	}
 	Comments interface {
 		Create(context.Context, *Comment) error
-		GetByPostID(context.Context, int64) ([]Comment, error)
+		GetByPostID(context.Context, int64, int64) ([]Comment, error)
+		GetByID(context.Context, int64, int64) (*Comment, error)
+		ListByPostID(context.Context, int64, int64, *paginate.CommentPaginateQuery) ([]Comment, error)
+		CountByPostID(context.Context, int64) (int64, error)
+		GetReplies(context.Context, int64, int64, *paginate.PaginatedQuery) ([]Comment, error)
+		Update(context.Context, *Comment) error
+		Delete(context.Context, int64) error
 	}
 	Followers interface {
 		Follow(context.Context, int64, int64) error
 		Unfollow(context.Context, int64, int64) error
+		GetAllFollowers(context.Context, int64) ([]User, error)
+		FollowTag(context.Context, int64, string) error
+		UnfollowTag(context.Context, int64, string) error
+		GetFollowedTags(context.Context, int64) ([]string, error)
+		GetFollowers(context.Context, int64, int64, *paginate.PaginatedQuery) ([]UserWithMetaData, error)
+		GetFollowing(context.Context, int64, int64, *paginate.PaginatedQuery) ([]UserWithMetaData, error)
+		RequestFollow(context.Context, int64, int64) error
+		ApproveRequest(context.Context, int64, int64) error
+		RejectRequest(context.Context, int64, int64) error
+		GetIncomingRequests(context.Context, int64, *paginate.PaginatedQuery) ([]FollowRequest, error)
+		GetOutgoingRequests(context.Context, int64, *paginate.PaginatedQuery) ([]FollowRequest, error)
+	}
+	Blocks interface {
+		Block(context.Context, int64, int64) error
+		Unblock(context.Context, int64, int64) error
+		Mute(context.Context, int64, int64) error
+		Unmute(context.Context, int64, int64) error
+		IsBlocked(context.Context, int64, int64) (bool, error)
+		GetBlocked(context.Context, int64, *paginate.PaginatedQuery) ([]User, error)
+		GetMuted(context.Context, int64, *paginate.PaginatedQuery) ([]User, error)
 	}
 	Roles interface {
 		GetRoleByName(context.Context, string) (*Role, error)
 	}
+	Likes interface {
+		LikePost(context.Context, int64, int64) error
+		UnlikePost(context.Context, int64, int64) error
+		LikeComment(context.Context, int64, int64) error
+		UnlikeComment(context.Context, int64, int64) error
+		HasLikedPost(context.Context, int64, int64) (bool, error)
+	}
+	Reactions interface {
+		Add(context.Context, int64, int64, string) error
+		Remove(context.Context, int64, int64, string) error
+		GetSummaries(context.Context, int64, []int64) (map[int64]ReactionSummary, error)
+	}
+	Bookmarks interface {
+		CreateList(context.Context, *ReadingList) error
+		GetLists(context.Context, int64) ([]ReadingList, error)
+		GetListByID(context.Context, int64) (*ReadingList, error)
+		DeleteList(context.Context, int64) error
+		AddPost(context.Context, int64, int64) error
+		RemovePost(context.Context, int64, int64) error
+		GetPosts(context.Context, int64, int64, *paginate.PostPaginateQuery) ([]PostWithMetaData, paginate.CursorPage, error)
+	}
+	Timelines interface {
+		Rebuild(context.Context, int64) error
+		RebuildAll(context.Context) error
+	}
+	Revisions interface {
+		GetByPostID(context.Context, int64) ([]PostRevision, error)
+		GetByVersion(context.Context, int64, int) (*PostRevision, error)
+	}
+	Tags interface {
+		List(context.Context, *paginate.PaginatedQuery) ([]TagCount, error)
+		Trending(context.Context, *paginate.TrendingTagQuery) ([]TagTrend, error)
+		Merge(context.Context, []string, string) (int64, error)
+	}
+	Search interface {
+		Users(context.Context, int64, *paginate.UnifiedSearchQuery) ([]User, error)
+		Posts(context.Context, int64, *paginate.UnifiedSearchQuery) ([]PostSuggestion, error)
+		Tags(context.Context, *paginate.UnifiedSearchQuery) ([]TagCount, error)
+	}
+	Media interface {
+		SetAvatar(context.Context, *Media) (*Media, error)
+		DeleteAvatar(context.Context, int64) (*Media, error)
+		AddPostImage(context.Context, int64, *Media) error
+		GetPostImages(context.Context, int64) ([]Media, error)
+		GetPostImage(context.Context, int64, int64) (*Media, error)
+		DeletePostImage(context.Context, int64, int64) (*Media, error)
+	}
 }
 
 func NewPostgresStore(db *sql.DB) Storage {
@@ -56,7 +144,16 @@ func NewPostgresStore(db *sql.DB) Storage {
 		Users:     &UserStore{db},
 		Comments:  &CommentStore{db},
 		Followers: &FollowerStore{db},
+		Blocks:    &BlockStore{db},
 		Roles:     &RoleStore{db: db},
+		Revisions: &RevisionStore{db},
+		Likes:     &LikeStore{db},
+		Reactions: &ReactionStore{db},
+		Bookmarks: &BookmarkStore{db},
+		Timelines: &TimelineStore{db},
+		Tags:      &TagStore{db},
+		Search:    &SearchStore{db},
+		Media:     &MediaStore{db},
 	}
 }
 
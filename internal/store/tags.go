@@ -0,0 +1,182 @@
+package store
+
+import (
+	"Blog/internal/store/paginate"
+	"context"
+	"database/sql"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+type TagStore struct {
+	db *sql.DB
+}
+
+type TagTrend struct {
+	Tag           string `json:"tag"`
+	Count         int64  `json:"count"`
+	PreviousCount int64  `json:"previous_count"`
+}
+
+// tagSpaceClass matches the whitespace between the words of a tag, the same
+// characters unicode.IsSpace reports. It is written with Postgres escapes so
+// the tag migrations split on exactly the same class.
+const tagSpaceClass = `[\t\n\v\f\r \u0085\u00a0\u1680\u2000-\u200a\u2028\u2029\u202f\u205f\u3000]+`
+
+var tagSpace = regexp.MustCompile(regexp.MustCompile(`\\u([0-9a-f]{4})`).ReplaceAllString(tagSpaceClass, `\x{$1}`))
+
+// NormalizeTag lower cases a tag, trims it and joins its words with dashes,
+// so " Machine  Learning" and "machine-learning" are the same tag.
+func NormalizeTag(tag string) string {
+	words := slices.DeleteFunc(tagSpace.Split(strings.ToLower(tag), -1), func(w string) bool {
+		return w == ""
+	})
+	return strings.Join(words, "-")
+}
+
+// NormalizeTags normalizes every tag, dropping empty ones and duplicates
+// while keeping the original order.
+func NormalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// List returns the tags used on public published posts with how many posts
+// use them, read from the tag_counts table the posts trigger keeps current.
+func (s *TagStore) List(ctx context.Context, pageQuery *paginate.PaginatedQuery) ([]TagCount, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `SELECT
+	tag,
+	uses
+FROM
+	tag_counts
+WHERE
+	tag ILIKE '%' || $1 || '%'
+ORDER BY
+	uses DESC, tag ASC
+LIMIT $2 OFFSET $3;`
+	rows, err := s.db.QueryContext(ctx, query, likeEscaper.Replace(pageQuery.Search), pageQuery.Limit, pageQuery.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tags := []TagCount{}
+	for rows.Next() {
+		var t TagCount
+		if err := rows.Scan(&t.Tag, &t.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, nil
+}
+
+// Trending ranks tags by how many posts used them over the last window,
+// alongside the count for the window before it.
+func (s *TagStore) Trending(ctx context.Context, trendQuery *paginate.TrendingTagQuery) ([]TagTrend, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	// counted in a subquery so the ordering can do arithmetic on the counts
+	query := `SELECT
+	tag,
+	uses,
+	previous_uses
+FROM (
+	SELECT
+		t.tag,
+		COUNT(*) FILTER (WHERE p.published_at >= NOW() - $1::float8 * INTERVAL '1 second') AS uses,
+		COUNT(*) FILTER (WHERE p.published_at < NOW() - $1::float8 * INTERVAL '1 second') AS previous_uses
+	FROM
+		posts p,
+		unnest(p.tags) AS t(tag)
+	WHERE
+		p.status = 'published'
+		AND p.visibility = 'public'
+		AND p.published_at >= NOW() - 2 * $1::float8 * INTERVAL '1 second'
+	GROUP BY
+		t.tag
+) counts
+WHERE
+	uses > 0
+ORDER BY
+	uses DESC,
+	uses - previous_uses DESC,
+	tag ASC
+LIMIT $2;`
+	rows, err := s.db.QueryContext(ctx, query, trendQuery.Window.Seconds(), trendQuery.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	trends := []TagTrend{}
+	for rows.Next() {
+		var t TagTrend
+		if err := rows.Scan(&t.Tag, &t.Count, &t.PreviousCount); err != nil {
+			return nil, err
+		}
+		trends = append(trends, t)
+	}
+	return trends, nil
+}
+
+// Merge replaces every source tag with target on all posts, keeping each
+// post's tags unique. Renaming a tag is a merge with a single source.
+// Returns ErrNotFound when no post uses any of the sources.
+func (s *TagStore) Merge(ctx context.Context, sources []string, target string) (int64, error) {
+	sources = NormalizeTags(sources)
+	target = NormalizeTag(target)
+	var updated int64
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+		query := `UPDATE posts p SET tags = ARRAY(
+		SELECT n.tag FROM (
+			SELECT CASE WHEN u.tag = ANY($1::varchar[]) THEN $2::varchar ELSE u.tag END AS tag,
+			MIN(u.ord) AS first
+			FROM unnest(p.tags) WITH ORDINALITY AS u(tag, ord)
+			GROUP BY 1
+		) n ORDER BY n.first
+	), updated_at = NOW()
+	WHERE p.tags && $1::varchar[]`
+		res, err := tx.ExecContext(ctx, query, pq.Array(sources), target)
+		if err != nil {
+			return err
+		}
+		rows_affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows_affected == 0 {
+			return ErrNotFound
+		}
+		updated = rows_affected
+		// readers following a source tag now follow the target
+		query = `INSERT INTO tag_followers (user_id, tag, created_at)
+		SELECT user_id, $2::varchar, MIN(created_at) FROM tag_followers
+		WHERE tag = ANY($1::varchar[]) GROUP BY user_id
+		ON CONFLICT DO NOTHING`
+		if _, err := tx.ExecContext(ctx, query, pq.Array(sources), target); err != nil {
+			return err
+		}
+		query = `DELETE FROM tag_followers WHERE tag = ANY($1::varchar[]) AND tag <> $2::varchar`
+		_, err = tx.ExecContext(ctx, query, pq.Array(sources), target)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return updated, nil
+}
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+)
+
+// TimelineStore keeps the timelines table, a copy of every reader's feed
+// written when posts are created and follows change, so reading the feed
+// doesn't have to join through followers.
+type TimelineStore struct {
+	db *sql.DB
+}
+
+// Rebuild recreates the timeline of a single reader.
+func (s *TimelineStore) Rebuild(ctx context.Context, userId int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM timelines WHERE user_id = $1`, userId); err != nil {
+			return err
+		}
+		query := `INSERT INTO timelines (user_id, post_id, author_id, created_at)
+		SELECT p.user_id, p.id, p.user_id, p.created_at FROM posts p WHERE p.user_id = $1
+		UNION
+		SELECT f.user_id, p.id, p.user_id, p.created_at FROM followers f
+			JOIN posts p ON p.user_id = f.follower_id
+		WHERE f.user_id = $1
+		ON CONFLICT DO NOTHING`
+		_, err := tx.ExecContext(ctx, query, userId)
+		return err
+	})
+}
+
+// RebuildAll recreates every timeline from posts and followers.
+func (s *TimelineStore) RebuildAll(ctx context.Context) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM timelines`); err != nil {
+			return err
+		}
+		query := `INSERT INTO timelines (user_id, post_id, author_id, created_at)
+		SELECT p.user_id, p.id, p.user_id, p.created_at FROM posts p
+		UNION
+		SELECT f.user_id, p.id, p.user_id, p.created_at FROM followers f
+			JOIN posts p ON p.user_id = f.follower_id
+		ON CONFLICT DO NOTHING`
+		_, err := tx.ExecContext(ctx, query)
+		return err
+	})
+}
+
+// fanOut adds a new post to the timelines of its author and their followers.
+func (s *TimelineStore) fanOut(ctx context.Context, tx *sql.Tx, post *Post) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `INSERT INTO timelines (user_id, post_id, author_id, created_at)
+	SELECT $2::bigint, $1::bigint, $2::bigint, $3::timestamptz
+	UNION
+	SELECT f.user_id, $1::bigint, $2::bigint, $3::timestamptz FROM followers f WHERE f.follower_id = $2::bigint
+	ON CONFLICT DO NOTHING`
+	_, err := tx.ExecContext(ctx, query, post.ID, post.UserId, post.CreatedAt)
+	return err
+}
+
+// backfill copies the posts of authorId into the timeline of readerId.
+func (s *TimelineStore) backfill(ctx context.Context, tx *sql.Tx, readerId int64, authorId int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `INSERT INTO timelines (user_id, post_id, author_id, created_at)
+	SELECT $1, p.id, p.user_id, p.created_at FROM posts p WHERE p.user_id = $2
+	ON CONFLICT DO NOTHING`
+	_, err := tx.ExecContext(ctx, query, readerId, authorId)
+	return err
+}
+
+// prune removes the posts of authorId from the timeline of readerId.
+func (s *TimelineStore) prune(ctx context.Context, tx *sql.Tx, readerId int64, authorId int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `DELETE FROM timelines WHERE user_id = $1 AND author_id = $2 AND author_id <> user_id`
+	_, err := tx.ExecContext(ctx, query, readerId, authorId)
+	return err
+}
@@ -6,8 +6,10 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
+	"errors"
 	"time"
 
+	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -18,8 +20,29 @@ type User struct {
 	Password  PasswordType `json:"-"`
 	CreatedAt string       `json:"created_at,omitempty"`
 	IsActive  bool         `json:"is_active,omitempty"`
+	IsPrivate bool         `json:"is_private"`
 	RoleID    int64        `json:"role_id"`
 	Role      Role         `json:"role"`
+	Profile
+	UsernameChangedAt *time.Time `json:"-"`
+	PasswordChangedAt *time.Time `json:"-"`
+}
+
+// Tokens in user_invitations are stored hashed and serve more than one
+// purpose, each looked up only for its own.
+const (
+	TokenPurposeActivation    = "activation"
+	TokenPurposePasswordReset = "password_reset"
+)
+
+type Profile struct {
+	DisplayName string `json:"display_name,omitempty"`
+	Bio         string `json:"bio,omitempty"`
+	Website     string `json:"website,omitempty"`
+	Location    string `json:"location,omitempty"`
+	// avatar urls come from the media row the user points at
+	AvatarURL          string `json:"avatar_url,omitempty"`
+	AvatarThumbnailURL string `json:"avatar_thumbnail_url,omitempty"`
 }
 
 type UserWithMetaData struct {
@@ -27,6 +50,12 @@ type UserWithMetaData struct {
 	Follower bool `json:"follower"`
 }
 
+type UserStats struct {
+	FollowerCount  int64 `json:"follower_count"`
+	FollowingCount int64 `json:"following_count"`
+	PostCount      int64 `json:"post_count"`
+}
+
 type PasswordType struct {
 	text *string
 	hash []byte
@@ -56,8 +85,11 @@ type UserStore struct {
 func (s *UserStore) Create(ctx context.Context, tx *sql.Tx, user *User) error {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
+	// usernames someone gave up recently are still reserved for them
 	query := `INSERT INTO users (Username,Email,Password,role_id) 
-			VALUES ($1 , $2 , $3 , (SELECT id FROM roles WHERE name = $4) ) RETURNING id,created_at`
+			SELECT $1::varchar , $2::citext , $3::bytea , (SELECT id FROM roles WHERE name = $4)
+			WHERE NOT EXISTS (SELECT 1 FROM reserved_usernames WHERE username = $1::varchar AND reserved_until > NOW())
+			RETURNING id,created_at`
 
 	role := user.Role.Name
 	if role == "" {
@@ -68,6 +100,8 @@ func (s *UserStore) Create(ctx context.Context, tx *sql.Tx, user *User) error {
 		&user.CreatedAt)
 	if err != nil {
 		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrDuplicateUsername
 		case err.Error() == `pq: duplicate key value violates unique constraint "users_email_key`:
 			return ErrDuplicateEmail
 		case err.Error() == `pq: duplicate key value violates unique constraint "users_username_key`:
@@ -87,7 +121,7 @@ func (s *UserStore) CreateAndInvite(ctx context.Context, user *User, token strin
 			return err
 		}
 		// create user invitation
-		if err := s.createUserInvitation(ctx, tx, token, exp, user.ID); err != nil {
+		if err := s.createUserInvitation(ctx, tx, token, TokenPurposeActivation, exp, user.ID); err != nil {
 			return err
 		}
 		return nil
@@ -113,13 +147,13 @@ func (s *UserStore) deleteUser(ctx context.Context, tx *sql.Tx, userId int64) er
 
 }
 
-func (s *UserStore) createUserInvitation(ctx context.Context, tx *sql.Tx, token string, exp time.Duration, UserId int64) error {
+func (s *UserStore) createUserInvitation(ctx context.Context, tx *sql.Tx, token string, purpose string, exp time.Duration, UserId int64) error {
 	query := `INSERT INTO user_invitations 
-	(token,user_id,expiry) VALUES
-		($1, $2, $3);`
+	(token,user_id,expiry,purpose) VALUES
+		($1, $2, $3, $4);`
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
-	_, err := tx.ExecContext(ctx, query, token, UserId, time.Now().Add(exp))
+	_, err := tx.ExecContext(ctx, query, token, UserId, time.Now().Add(exp), purpose)
 	if err != nil {
 		return err
 	}
@@ -147,9 +181,16 @@ func (s *UserStore) GetUserByEmail(ctx context.Context, email string) (*User, er
 func (s *UserStore) GetUserById(ctx context.Context, UserId int64) (*User, error) {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
-	query := `SELECT users.id,username , email, created_at , is_active, role_id , roles.* FROM users JOIN roles ON (users.role_id = roles.id) WHERE users.id = $1`
+	query := `SELECT users.id,username , email, users.created_at , is_active, is_private,
+	display_name , bio , website , location , username_changed_at , password_changed_at ,
+	COALESCE(avatar.url, '') , COALESCE(avatar.thumbnail_url, '') , role_id , roles.*
+	FROM users JOIN roles ON (users.role_id = roles.id)
+	LEFT JOIN media avatar ON avatar.id = users.avatar_id
+	WHERE users.id = $1`
 	user := &User{}
-	err := s.db.QueryRowContext(ctx, query, UserId).Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt, &user.IsActive, &user.RoleID, &user.Role.ID, &user.Role.Name, &user.Role.Level, &user.Role.Description)
+	err := s.db.QueryRowContext(ctx, query, UserId).Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt, &user.IsActive, &user.IsPrivate,
+		&user.DisplayName, &user.Bio, &user.Website, &user.Location, &user.UsernameChangedAt, &user.PasswordChangedAt,
+		&user.AvatarURL, &user.AvatarThumbnailURL, &user.RoleID, &user.Role.ID, &user.Role.Name, &user.Role.Level, &user.Role.Description)
 	if err != nil {
 		switch err {
 		case sql.ErrNoRows:
@@ -165,7 +206,7 @@ func (s *UserStore) GetUserById(ctx context.Context, UserId int64) (*User, error
 func (s *UserStore) Activate(ctx context.Context, token string) error {
 
 	return withTx(s.db, ctx, func(tx *sql.Tx) error {
-		user, err := s.getUserFromInvitation(ctx, tx, token)
+		user, err := s.getUserFromInvitation(ctx, tx, token, TokenPurposeActivation)
 		if err != nil {
 			return err
 		}
@@ -206,15 +247,15 @@ func (s *UserStore) update(ctx context.Context, tx *sql.Tx, user *User) error {
 	return nil
 }
 
-func (s *UserStore) getUserFromInvitation(ctx context.Context, tx *sql.Tx, token string) (*User, error) {
+func (s *UserStore) getUserFromInvitation(ctx context.Context, tx *sql.Tx, token string, purpose string) (*User, error) {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 	query := `SELECT u.id , u.username , u.email , u.created_at , u.is_active FROM users u JOIN user_invitations
-	ui ON u.id = ui.user_id WHERE ui.token = $1 AND ui.expiry > $2`
+	ui ON u.id = ui.user_id WHERE ui.token = $1 AND ui.expiry > $2 AND ui.purpose = $3`
 	user := &User{}
 	hash := sha256.Sum256([]byte(token))
 	hashedToken := hex.EncodeToString(hash[:])
-	err := tx.QueryRowContext(ctx, query, hashedToken, time.Now()).Scan(&user.ID,
+	err := tx.QueryRowContext(ctx, query, hashedToken, time.Now(), purpose).Scan(&user.ID,
 		&user.Username, &user.Email,
 		&user.CreatedAt, &user.IsActive)
 	if err != nil {
@@ -228,21 +269,96 @@ func (s *UserStore) getUserFromInvitation(ctx context.Context, tx *sql.Tx, token
 	return user, nil
 }
 
-func (s *UserStore) Delete(ctx context.Context, userId int64) error {
+// CreatePasswordReset stores a hashed reset token for the active account
+// with this email, replacing any reset still pending, and returns the user
+// so the caller can mail them.
+func (s *UserStore) CreatePasswordReset(ctx context.Context, email string, token string, exp time.Duration) (*User, error) {
+	user, err := s.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	err = withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := s.deleteUserTokens(ctx, tx, user.ID, TokenPurposePasswordReset); err != nil {
+			return err
+		}
+		return s.createUserInvitation(ctx, tx, token, TokenPurposePasswordReset, exp, user.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
 
+// ResetPassword sets a new password for the owner of a reset token. Every
+// token the user holds is revoked, and bearer tokens issued before now stop
+// working through password_changed_at.
+func (s *UserStore) ResetPassword(ctx context.Context, token string, password *PasswordType) error {
 	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		user, err := s.getUserFromInvitation(ctx, tx, token, TokenPurposePasswordReset)
+		if err != nil {
+			return err
+		}
+		if err := s.updatePassword(ctx, tx, user.ID, password); err != nil {
+			return err
+		}
+		return s.deleteUserInvitations(ctx, tx, user.ID)
+	})
+}
+
+func (s *UserStore) updatePassword(ctx context.Context, tx *sql.Tx, userId int64, password *PasswordType) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `UPDATE users SET password = $1 , password_changed_at = date_trunc('second', NOW()) WHERE id = $2`
+	res, err := tx.ExecContext(ctx, query, password.hash, userId)
+	if err != nil {
+		return err
+	}
+	rows_affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows_affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *UserStore) deleteUserTokens(ctx context.Context, tx *sql.Tx, userId int64, purpose string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `DELETE FROM user_invitations WHERE user_id = $1 AND purpose = $2`
+	_, err := tx.ExecContext(ctx, query, userId, purpose)
+	return err
+}
+
+// Delete removes a user with everything they own and returns the media that
+// went with them: their uploads and the images on their posts. The caller
+// still has to remove the files.
+func (s *UserStore) Delete(ctx context.Context, userId int64) ([]Media, error) {
+	var media []Media
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
 		// deleting the user invitation
 		err := s.deleteUserInvitations(ctx, tx, userId)
 		if err != nil {
 			return err
 		}
 
+		media, err = deleteMedia(ctx, tx, `m.user_id = $1 OR m.id IN (
+		SELECT pi.media_id FROM post_images pi JOIN posts p ON p.id = pi.post_id WHERE p.user_id = $1)`, userId)
+		if err != nil {
+			return err
+		}
+
 		err = s.deleteUser(ctx, tx, userId)
 		if err != nil {
 			return err
 		}
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+	return media, nil
 }
 
 func (s *UserStore) SearchFriends(ctx context.Context, userId int64, friendQuery *paginate.FriendPaginateQuery) ([]UserWithMetaData, error) {
@@ -266,6 +382,7 @@ FROM
 WHERE
 	u.id != $1 
 	AND r.name = $2 
+	AND ` + notBlocked("$1::bigint", "u.id") + `
 	AND (
 	u.username ILIKE '%' || $3 || '%'
 	)
@@ -287,3 +404,102 @@ OFFSET $5;
 	}
 	return list, nil
 }
+
+// GetStats counts who follows userId, who they follow and their published posts.
+func (s *UserStore) GetStats(ctx context.Context, userId int64) (*UserStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+	query := `SELECT
+	(SELECT COUNT(*) FROM followers f JOIN users u ON u.id = f.user_id
+		WHERE f.follower_id = $1 AND u.is_active = true),
+	(SELECT COUNT(*) FROM followers f JOIN users u ON u.id = f.follower_id
+		WHERE f.user_id = $1 AND u.is_active = true),
+	(SELECT COUNT(*) FROM posts p WHERE p.user_id = $1 AND p.status = 'published')`
+	var stats UserStats
+	err := s.db.QueryRowContext(ctx, query, userId).Scan(&stats.FollowerCount, &stats.FollowingCount, &stats.PostCount)
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// SetPrivate switches the account between private and public. Going public
+// accepts every pending follow request.
+func (s *UserStore) SetPrivate(ctx context.Context, userId int64, private bool) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+		query := `UPDATE users SET is_private = $1 WHERE id = $2`
+		if _, err := tx.ExecContext(ctx, query, private, userId); err != nil {
+			return err
+		}
+		if private {
+			return nil
+		}
+		followers := &FollowerStore{s.db}
+		return followers.acceptAll(ctx, tx, userId)
+	})
+}
+
+// UpdateProfile saves the user's username and profile. A new username is
+// refused with ErrUsernameTooSoon within cooldown of the last change, and the
+// old one stays reserved for the user for reservation.
+func (s *UserStore) UpdateProfile(ctx context.Context, user *User, cooldown time.Duration, reservation time.Duration) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+		var current string
+		var changedAt *time.Time
+		query := `SELECT username , username_changed_at FROM users WHERE id = $1 FOR UPDATE`
+		if err := tx.QueryRowContext(ctx, query, user.ID).Scan(&current, &changedAt); err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+		if user.Username != current {
+			if err := s.changeUsername(ctx, tx, user, current, changedAt, cooldown, reservation); err != nil {
+				return err
+			}
+		}
+		query = `UPDATE users SET display_name = $1 , bio = $2 , website = $3 , location = $4 WHERE id = $5`
+		_, err := tx.ExecContext(ctx, query, user.DisplayName, user.Bio, user.Website, user.Location, user.ID)
+		return err
+	})
+}
+
+func (s *UserStore) changeUsername(ctx context.Context, tx *sql.Tx, user *User, current string, changedAt *time.Time, cooldown time.Duration, reservation time.Duration) error {
+	if changedAt != nil && time.Since(*changedAt) < cooldown {
+		return ErrUsernameTooSoon
+	}
+	var reserved bool
+	query := `SELECT EXISTS (SELECT 1 FROM reserved_usernames
+		WHERE username = $1 AND user_id <> $2 AND reserved_until > NOW())`
+	if err := tx.QueryRowContext(ctx, query, user.Username, user.ID).Scan(&reserved); err != nil {
+		return err
+	}
+	if reserved {
+		return ErrDuplicateUsername
+	}
+	// taking back a name of your own releases its reservation
+	query = `DELETE FROM reserved_usernames WHERE username = $1`
+	if _, err := tx.ExecContext(ctx, query, user.Username); err != nil {
+		return err
+	}
+	query = `INSERT INTO reserved_usernames (username, user_id, reserved_until)
+	VALUES ( $1 , $2 , NOW() + make_interval(secs => $3::float8) )
+	ON CONFLICT (username) DO UPDATE SET user_id = EXCLUDED.user_id, reserved_until = EXCLUDED.reserved_until`
+	if _, err := tx.ExecContext(ctx, query, current, user.ID, reservation.Seconds()); err != nil {
+		return err
+	}
+	query = `UPDATE users SET username = $1 , username_changed_at = NOW() WHERE id = $2 RETURNING username_changed_at`
+	if err := tx.QueryRowContext(ctx, query, user.Username, user.ID).Scan(&user.UsernameChangedAt); err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return ErrDuplicateUsername
+		}
+		return err
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"sync"
 )
 
@@ -14,7 +15,9 @@ type UpdatedPostPayload struct {
 	Content string `json:"content,omitempty"`
 }
 
-func updatePost(postId int64, p UpdatedPostPayload, wg *sync.WaitGroup) {
+// updatePost sends an edit based on the given ETag. When two editors send the
+// same ETag, one gets 200 and the other 412 Precondition Failed.
+func updatePost(postId int64, etag string, p UpdatedPostPayload, wg *sync.WaitGroup) {
 	url := fmt.Sprintf("http://localhost:3002/v1/posts/%d", postId)
 	fmt.Println(url)
 	b, _ := json.Marshal(p)
@@ -25,6 +28,8 @@ func updatePost(postId int64, p UpdatedPostPayload, wg *sync.WaitGroup) {
 		return
 	}
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+os.Getenv("TOKEN"))
+	req.Header.Set("If-Match", etag)
 	client := &http.Client{}
 	resq, err := client.Do(req)
 	if err != nil {
@@ -33,20 +38,40 @@ func updatePost(postId int64, p UpdatedPostPayload, wg *sync.WaitGroup) {
 		return
 	}
 	defer resq.Body.Close()
-	fmt.Printf("updated response status : %+v", resq.Status)
+	fmt.Printf("updated response status : %+v\n", resq.Status)
 	wg.Done()
 
 }
 
+func getPostETag(postId int64) (string, error) {
+	url := fmt.Sprintf("http://localhost:3002/v1/posts/%d", postId)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+os.Getenv("TOKEN"))
+	client := &http.Client{}
+	resq, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resq.Body.Close()
+	return resq.Header.Get("ETag"), nil
+}
+
 func main() {
 	var wg sync.WaitGroup
 	postId := 3
-	wg.Add(1)
+	etag, err := getPostETag(int64(postId))
+	if err != nil {
+		log.Fatalf("error fetching post : %+v", err)
+	}
+	wg.Add(2)
 	content := "New Content from User B "
-	// title := "New title from User A "
+	title := "New title from User A "
 
-	// go updatePost(int64(postId), UpdatedPostPayload{Title: title}, &wg)
-	go updatePost(int64(postId), UpdatedPostPayload{Content: content}, &wg)
+	go updatePost(int64(postId), etag, UpdatedPostPayload{Title: title}, &wg)
+	go updatePost(int64(postId), etag, UpdatedPostPayload{Content: content}, &wg)
 
 	wg.Wait()
 }